@@ -2,9 +2,21 @@ package beacon
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SDK identifies this client to ingestion in the X-Beacon-SDK header.
+const (
+	SDKName    = "beacon-go"
+	SDKVersion = "0.2.0"
 )
 
 type Config struct {
@@ -13,9 +25,28 @@ type Config struct {
 	IngestURL string
 	Environment string
 	Release string
+	// Transport delivers events. When nil they are POSTed as JSON to
+	// IngestURL; see NewGRPCTransport for the gRPC alternative.
+	Transport Transport
 }
 
+// Transport delivers events to ingestion.
+type Transport interface {
+	Send(event *Event) error
+}
+
+// batchTransport is implemented by transports that can deliver several
+// events in one call.
+type batchTransport interface {
+	SendBatch(events []*Event) error
+}
+
+// maxBatch bounds how many queued events the worker hands to a
+// batchTransport at once.
+const maxBatch = 100
+
 type Event struct {
+	EventID     string            `json:"event_id,omitempty"`
 	Timestamp   time.Time         `json:"timestamp"`
 	Level       string            `json:"level"`
 	Message     string            `json:"message"`
@@ -23,28 +54,123 @@ type Event struct {
 	Environment string            `json:"environment"`
 	Release     string            `json:"release"`
 	Tags        map[string]string `json:"tags,omitempty"`
+	// Fingerprint overrides how the event is grouped into an issue: events
+	// with the same fingerprint share an issue. Include DefaultFingerprint to
+	// extend the server's grouping instead of replacing it, e.g.
+	// []string{DefaultFingerprint, "upstream-timeout"}.
+	Fingerprint []string          `json:"fingerprint,omitempty"`
 }
 
+// DefaultFingerprint stands for the fingerprint the server would compute.
+const DefaultFingerprint = "{{ default }}"
+
 
 type Client struct{
 	config Config
-	http *http.Client
+	transport Transport
 	Queue chan *Event
 }
 
 func(c *Client) worker(){
+	batcher, canBatch := c.transport.(batchTransport)
+
 	for event := range c.Queue{
-		c.send(event)
+		if !canBatch {
+			c.send(event)
+			continue
+		}
+
+		// Take whatever else is already queued so a burst goes out in one call.
+		batch := []*Event{event}
+	drain:
+		for len(batch) < maxBatch {
+			select {
+			case next, ok := <-c.Queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+
+		for _, e := range batch {
+			if e.EventID == "" {
+				e.EventID = newEventID()
+			}
+		}
+		retry(func() error { return batcher.SendBatch(batch) })
+	}
+}
+
+// Failed sends are retried with the same event IDs, so ingestion drops the
+// copies of events that did get through.
+const (
+	maxSendAttempts = 3
+	retryBackoff    = 500 * time.Millisecond
+)
+
+// statusError is an HTTP response ingestion did not accept.
+type statusError struct {
+	Code   int
+	Status string
+}
+
+func (e *statusError) Error() string {
+	return "beacon: ingestion responded " + e.Status
+}
+
+// temporary reports whether a failed send may succeed if retried. Requests
+// ingestion rejected as invalid or unauthorised never will.
+func temporary(err error) bool {
+	var httpErr *statusError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code == http.StatusTooManyRequests || httpErr.Code >= 500
+	}
+
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted:
+			return true
+		}
+		return false
+	}
+
+	// Network errors.
+	return true
+}
+
+// retry calls send until it succeeds, fails for good or has been tried
+// maxSendAttempts times, doubling the wait between attempts.
+func retry(send func() error) {
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || !temporary(err) || attempt == maxSendAttempts {
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
 	}
 }
 
 
 func Init(config Config) *Client {
+	transport := config.Transport
+	if transport == nil {
+		transport = &HTTPTransport{
+			URL:    config.IngestURL,
+			APIKey: config.APIKey,
+			Client: &http.Client{
+				Timeout: 3 * time.Second,
+			},
+		}
+	}
+
 	client := &Client{
 		config: config,
-		http: &http.Client{
-			Timeout: 3 * time.Second,
-		},
+		transport: transport,
 		Queue: make(chan *Event, 100),
 	}
 
@@ -53,19 +179,58 @@ func Init(config Config) *Client {
 }
 
 
+// newEventID returns a random UUIDv4 used to de-duplicate retried sends.
+func newEventID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return ""
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
 func (c *Client) send(event *Event){
-	reqBody, _ := json.Marshal(event)
+	if event.EventID == "" {
+		event.EventID = newEventID()
+	}
 
-	req, _ := http.NewRequest("POST", c.config.IngestURL, bytes.NewBuffer(reqBody))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	retry(func() error { return c.transport.Send(event) })
+}
+
+// HTTPTransport POSTs events as JSON to the ingestion /events endpoint.
+type HTTPTransport struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func (t *HTTPTransport) Send(event *Event) error {
+	reqBody, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
 
-	resp , err := c.http.Do(req)
-	if err != nil{
-		return
+	req, err := http.NewRequest("POST", t.URL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	req.Header.Set("X-Beacon-SDK", SDKName+"/"+SDKVersion)
 
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
 	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return &statusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
 }
 
 
@@ -0,0 +1,95 @@
+package beacon
+
+import (
+	"context"
+	"time"
+
+	"github.com/k1ngalph0x/beacon/sdk/ingestpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCTransport sends events over the ingestion gRPC API. Bursts of queued
+// events are sent on a single SendEvents stream.
+type GRPCTransport struct {
+	conn    *grpc.ClientConn
+	client  ingestpb.IngestServiceClient
+	apiKey  string
+	timeout time.Duration
+}
+
+// NewGRPCTransport connects to the ingestion gRPC endpoint at target, e.g.
+// "ingestion:8093". opts must include transport credentials, such as
+// grpc.WithTransportCredentials(insecure.NewCredentials()) inside a mesh
+// that already encrypts traffic.
+func NewGRPCTransport(target, apiKey string, opts ...grpc.DialOption) (*GRPCTransport, error) {
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GRPCTransport{
+		conn:    conn,
+		client:  ingestpb.NewIngestServiceClient(conn),
+		apiKey:  apiKey,
+		timeout: 3 * time.Second,
+	}, nil
+}
+
+func (t *GRPCTransport) Close() error {
+	return t.conn.Close()
+}
+
+func (t *GRPCTransport) context() (context.Context, context.CancelFunc) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Bearer "+t.apiKey,
+		"x-beacon-sdk", SDKName+"/"+SDKVersion,
+	)
+	return context.WithTimeout(ctx, t.timeout)
+}
+
+func toProto(event *Event) *ingestpb.SendEventRequest {
+	return &ingestpb.SendEventRequest{
+		Event: &ingestpb.Event{
+			EventId:     event.EventID,
+			Timestamp:   timestamppb.New(event.Timestamp),
+			Level:       event.Level,
+			Message:     event.Message,
+			StackTrace:  event.StackTrace,
+			Environment: event.Environment,
+			Release:     event.Release,
+			Tags:        event.Tags,
+			Fingerprint: event.Fingerprint,
+		},
+	}
+}
+
+func (t *GRPCTransport) Send(event *Event) error {
+	ctx, cancel := t.context()
+	defer cancel()
+
+	_, err := t.client.SendEvent(ctx, toProto(event))
+	return err
+}
+
+func (t *GRPCTransport) SendBatch(events []*Event) error {
+	ctx, cancel := t.context()
+	defer cancel()
+
+	stream, err := t.client.SendEvents(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		err = stream.Send(toProto(event))
+		if err != nil {
+			// The server ended the stream; CloseAndRecv reports why.
+			break
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+	return err
+}
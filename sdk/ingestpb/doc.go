@@ -0,0 +1,5 @@
+// Package ingestpb holds the gRPC ingestion API shared by the SDK and
+// ingestion-service.
+package ingestpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ingest.proto
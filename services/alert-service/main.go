@@ -19,6 +19,24 @@ type IssueUpdate struct {
 	Count     int    `json:"count"`
 	Level     string `json:"level"`
 	Status    string `json:"status"`
+	Assignee  string `json:"assignee"`
+}
+
+type IssueAssignment struct {
+	IssueID          string `json:"issue_id"`
+	ProjectID        string `json:"project_id"`
+	Assignee         string `json:"assignee"`
+	PreviousAssignee string `json:"previous_assignee"`
+	Source           string `json:"source"`
+}
+
+type IssueStateChange struct {
+	IssueID    string `json:"issue_id"`
+	ProjectID  string `json:"project_id"`
+	Transition string `json:"transition"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Release    string `json:"release"`
 }
 
 
@@ -39,6 +57,8 @@ func main() {
 	}
 
 	go startConsumer(conn)
+	go startStateConsumer()
+	go startAssignmentConsumer()
 
 	select {} 
 }
@@ -66,6 +86,65 @@ func startConsumer(conn *gorm.DB) {
 	}
 }
 
+// startStateConsumer notifies when a resolved issue regresses.
+func startStateConsumer() {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: []string{"localhost:9092"},
+		Topic:   "issue-state-changes",
+		GroupID: "alert-state-consumers",
+	})
+
+	for {
+		msg, err := reader.ReadMessage(context.Background())
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		var change IssueStateChange
+		if err := json.Unmarshal(msg.Value, &change); err != nil {
+			continue
+		}
+
+		if change.Transition == "regressed" {
+			if change.Release != "" {
+				log.Printf("ISSUE REGRESSED: Issue %s was %s and reappeared in release %s",
+					change.IssueID, change.From, change.Release)
+			} else {
+				log.Printf("ISSUE REGRESSED: Issue %s was %s and reappeared",
+					change.IssueID, change.From)
+			}
+		}
+	}
+}
+
+// startAssignmentConsumer notifies owners of issues assigned to them.
+func startAssignmentConsumer() {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: []string{"localhost:9092"},
+		Topic:   "issue-assignments",
+		GroupID: "alert-assignment-consumers",
+	})
+
+	for {
+		msg, err := reader.ReadMessage(context.Background())
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		var assignment IssueAssignment
+		if err := json.Unmarshal(msg.Value, &assignment); err != nil {
+			continue
+		}
+
+		if assignment.Assignee != "" {
+			log.Printf("ISSUE ASSIGNED: Issue %s assigned to %s (%s)",
+				assignment.IssueID, assignment.Assignee, assignment.Source)
+		}
+	}
+}
+
 func checkAlerts(conn *gorm.DB, update IssueUpdate){
 	var rules []models.AlertRule
 
@@ -77,8 +156,13 @@ func checkAlerts(conn *gorm.DB, update IssueUpdate){
 
 		if update.Level == rule.Level && update.Count >= rule.Threshold {
 
-			log.Printf("ALERT TRIGGERED: Issue %s reached %d",
-				update.IssueID, update.Count)
+			owner := update.Assignee
+			if owner == "" {
+				owner = "unassigned"
+			}
+
+			log.Printf("ALERT TRIGGERED: Issue %s reached %d, notifying %s",
+				update.IssueID, update.Count, owner)
 
 		}
 	}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/k1ngalph0x/beacon/services/auth-service/models"
+)
+
+type InboundFilterRequest struct {
+	Type        string `json:"type" binding:"required"`
+	Pattern     string `json:"pattern" binding:"required"`
+	Description string `json:"description"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+type UpdateInboundFilterRequest struct {
+	Pattern     *string `json:"pattern"`
+	Description *string `json:"description"`
+	Enabled     *bool   `json:"enabled"`
+}
+
+// normalizeFilterPattern validates a pattern for its filter type and returns
+// it in the form ingestion expects.
+func normalizeFilterPattern(filterType, pattern string) (string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return "", errors.New("pattern is required")
+	}
+
+	switch filterType {
+	case models.FilterMessage:
+		_, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", errors.New("pattern is not a valid regular expression")
+		}
+	case models.FilterLevel:
+		pattern = strings.ToLower(pattern)
+	case models.FilterEnvironment, models.FilterRelease:
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return "", errors.New("pattern is not a valid glob")
+		}
+	case models.FilterIP:
+		if ip := net.ParseIP(pattern); ip != nil {
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			return (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}).String(), nil
+		}
+		_, network, err := net.ParseCIDR(pattern)
+		if err != nil {
+			return "", errors.New("pattern is not a valid IP address or CIDR")
+		}
+		pattern = network.String()
+	default:
+		return "", errors.New("type must be one of message, level, environment, release, ip")
+	}
+
+	return pattern, nil
+}
+
+// ownedProject loads the :id project if it belongs to the signed-in user,
+// writing the error response otherwise.
+func (h *Handler) ownedProject(c *gin.Context) (*models.Project, bool) {
+	var project models.Project
+
+	userId, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	result := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userId).First(&project)
+	if result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+
+	return &project, true
+}
+
+func (h *Handler) ListInboundFilters(c *gin.Context) {
+	var filters []models.InboundFilter
+
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+
+	result := h.DB.Where("project_id = ?", project.ID).Order("created_at").Find(&filters)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch filters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filters": filters})
+}
+
+func (h *Handler) CreateInboundFilter(c *gin.Context) {
+	var req InboundFilterRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+
+	filterType := strings.ToLower(strings.TrimSpace(req.Type))
+	pattern, err := normalizeFilterPattern(filterType, req.Pattern)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := models.InboundFilter{
+		ProjectID:   project.ID,
+		Type:        filterType,
+		Pattern:     pattern,
+		Description: req.Description,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}
+
+	result := h.DB.Create(&filter)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create filter"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Filter created", "filter": filter})
+}
+
+func (h *Handler) UpdateInboundFilter(c *gin.Context) {
+	var req UpdateInboundFilterRequest
+	var filter models.InboundFilter
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+
+	result := h.DB.Where("id = ? AND project_id = ?", c.Param("filter_id"), project.ID).First(&filter)
+	if result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Filter not found"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Pattern != nil {
+		pattern, err := normalizeFilterPattern(filter.Type, *req.Pattern)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.Pattern = pattern
+		updates["pattern"] = pattern
+	}
+	if req.Description != nil {
+		filter.Description = *req.Description
+		updates["description"] = filter.Description
+	}
+	if req.Enabled != nil {
+		filter.Enabled = *req.Enabled
+		updates["enabled"] = filter.Enabled
+	}
+
+	if len(updates) > 0 {
+		result = h.DB.Model(&filter).Updates(updates)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update filter"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Filter updated", "filter": filter})
+}
+
+func (h *Handler) DeleteInboundFilter(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+
+	result := h.DB.Where("id = ? AND project_id = ?", c.Param("filter_id"), project.ID).Delete(&models.InboundFilter{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete filter"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Filter not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Filter deleted"})
+}
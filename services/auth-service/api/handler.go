@@ -34,6 +34,15 @@ type SignInRequest struct{
 
 type CreateProjectRequest struct {
 	Name string `json:"name" binding:"required,min=2,max=100"`
+	AllowedOrigins []string `json:"allowed_origins"`
+}
+
+type UpdateOriginsRequest struct {
+	AllowedOrigins []string `json:"allowed_origins" binding:"required"`
+}
+
+type UpdateAttachmentQuotaRequest struct {
+	AttachmentQuotaBytes *int64 `json:"attachment_quota_bytes" binding:"required,min=0"`
 }
 
 
@@ -311,6 +320,7 @@ func(h *Handler) CreateProject(c *gin.Context){
 		PublicKey: publicKey,
 		SecretKey: secretKey,
 		IsActive: true,
+		AllowedOrigins: normalizeOrigins(req.AllowedOrigins),
 	}
 
 	result := h.DB.Create(&project)
@@ -326,6 +336,94 @@ func(h *Handler) CreateProject(c *gin.Context){
 			"name":       project.Name,
 			"public_key": project.PublicKey,
 			"secret_key": project.SecretKey,
+			"allowed_origins": project.AllowedOrigins,
+		},
+	})
+}
+
+func normalizeOrigins(origins []string) []string {
+	normalized := []string{}
+	for _, origin := range origins {
+		origin = strings.TrimRight(strings.ToLower(strings.TrimSpace(origin)), "/")
+		if origin != "" {
+			normalized = append(normalized, origin)
+		}
+	}
+	return normalized
+}
+
+func(h *Handler) UpdateAllowedOrigins(c *gin.Context){
+	var req UpdateOriginsRequest
+	var project models.Project
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userId, exists := c.Get("user_id")
+	if !exists{
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	result := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userId).First(&project)
+	if result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	project.AllowedOrigins = normalizeOrigins(req.AllowedOrigins)
+	result = h.DB.Model(&project).Update("allowed_origins", project.AllowedOrigins)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Allowed origins updated",
+		"project": gin.H{
+			"id":              project.ID,
+			"allowed_origins": project.AllowedOrigins,
 		},
 	})
-}
\ No newline at end of file
+}
+
+func(h *Handler) UpdateAttachmentQuota(c *gin.Context){
+	var req UpdateAttachmentQuotaRequest
+	var project models.Project
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userId, exists := c.Get("user_id")
+	if !exists{
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	result := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userId).First(&project)
+	if result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	project.AttachmentQuotaBytes = *req.AttachmentQuotaBytes
+	result = h.DB.Model(&project).Update("attachment_quota_bytes", project.AttachmentQuotaBytes)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attachment quota updated",
+		"project": gin.H{
+			"id":                     project.ID,
+			"attachment_quota_bytes": project.AttachmentQuotaBytes,
+		},
+	})
+}
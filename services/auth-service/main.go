@@ -37,6 +37,11 @@ func main() {
 		log.Fatalf("Failed to migrate project table: %v", err)
 	}
 
+	err = conn.AutoMigrate(&models.InboundFilter{})
+	if err != nil {
+		log.Fatalf("Failed to migrate inbound filter table: %v", err)
+	}
+
 	handler := api.NewHandler(conn, config)
 	authMiddleware := middleware.NewAuthMiddleware(config.TOKEN.JwtKey)
 
@@ -55,6 +60,12 @@ func main() {
 	{
 		//user.POST("/onboard", handler.Onboard)
 		user.POST("/project", handler.CreateProject)
+		user.PUT("/project/:id/origins", handler.UpdateAllowedOrigins)
+		user.PUT("/project/:id/attachment-quota", handler.UpdateAttachmentQuota)
+		user.GET("/project/:id/filters", handler.ListInboundFilters)
+		user.POST("/project/:id/filters", handler.CreateInboundFilter)
+		user.PUT("/project/:id/filters/:filter_id", handler.UpdateInboundFilter)
+		user.DELETE("/project/:id/filters/:filter_id", handler.DeleteInboundFilter)
 	}
 
 	router.Run(":8080")
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
@@ -33,12 +34,49 @@ type Project struct {
 	PublicKey string    `gorm:"unique;not null" json:"public_key"`
 	SecretKey string    `gorm:"unique;not null" json:"-"`
 	IsActive  bool      `gorm:"default:true" json:"is_active"`
+	// Origins allowed to report from a browser with the public key, e.g.
+	// "https://app.example.com", "*.example.com" or "*".
+	AllowedOrigins pq.StringArray `gorm:"type:text[]" json:"allowed_origins"`
+	// Bytes of attachments the project may store. Zero means the ingestion
+	// service default.
+	AttachmentQuotaBytes int64 `gorm:"not null;default:0" json:"attachment_quota_bytes"`
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 	User      User      `gorm:"foreignKey:UserId;references:UserId;constraint:OnDelete:CASCADE" json:"-"`
 }
 
 
 
+// Inbound filter types. Events matching an enabled filter are dropped at
+// ingestion before they reach Kafka.
+const (
+	FilterMessage     = "message"     // Pattern is a regular expression matched against the message
+	FilterLevel       = "level"       // Pattern is a level name, e.g. "warning"
+	FilterEnvironment = "environment" // Pattern is a glob, e.g. "staging-*"
+	FilterRelease     = "release"     // Pattern is a glob, e.g. "1.4.*"
+	FilterIP          = "ip"          // Pattern is a CIDR or a single address
+)
+
+type InboundFilter struct {
+	ID            string     `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID     string     `gorm:"type:uuid;not null;index" json:"project_id"`
+	Type          string     `gorm:"not null" json:"type"`
+	Pattern       string     `gorm:"not null" json:"pattern"`
+	Description   string     `json:"description"`
+	Enabled       bool       `gorm:"not null" json:"enabled"`
+	// Maintained by ingestion-service.
+	DroppedCount  int64      `gorm:"not null;default:0" json:"dropped_count"`
+	LastDroppedAt *time.Time `json:"last_dropped_at"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	Project       Project    `gorm:"foreignKey:ProjectID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (f *InboundFilter) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == "" {
+		f.ID = uuid.New().String()
+	}
+	return nil
+}
+
 func(p *Project) BeforeCreate(tx *gorm.DB) error {
 	if p.ID == ""{
 		p.ID = uuid.New().String()
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/segmentio/kafka-go"
+)
+
+type Config struct {
+	SERVER ServerConfig
+	DB     PostgresConfig
+	AUTH   AuthConfig
+	KAFKA  KafkaConfig
+	DEDUP  DedupConfig
+	WAL    WALConfig
+	BLOBS  BlobConfig
+	FILTER FilterConfig
+	ENRICH EnrichConfig
+}
+
+type PostgresConfig struct {
+	Host     string
+	Dbname   string
+	Username string
+	Password string
+	Url      string
+	Port     string
+}
+
+type AuthConfig struct {
+	ProjectCacheTTL time.Duration
+}
+
+type ServerConfig struct {
+	Addr            string
+	GRPCAddr        string
+	PublishTimeout  time.Duration
+	MaxInFlight     int
+	ShutdownTimeout time.Duration
+	MaxEventBytes   int64
+	// Proxies whose X-Forwarded-For header is believed when resolving the
+	// client IP. Empty means the connection's address is always used.
+	TrustedProxies []string
+}
+
+type KafkaConfig struct {
+	Brokers      []string
+	Topic        string
+	BatchSize    int
+	BatchTimeout time.Duration
+	RequiredAcks kafka.RequiredAcks
+	Compression  kafka.Compression
+	DialTimeout  time.Duration
+}
+
+type DedupConfig struct {
+	Window time.Duration
+}
+
+type WALConfig struct {
+	Dir              string
+	MaxBytes         int64
+	SegmentBytes     int64
+	MaxReplayBackoff time.Duration
+}
+
+type EnrichConfig struct {
+	GeoIPDatabase string
+}
+
+type FilterConfig struct {
+	CacheTTL      time.Duration
+	FlushInterval time.Duration
+}
+
+type BlobConfig struct {
+	Store             string
+	Dir               string
+	MaxUploadBytes    int64
+	DefaultQuotaBytes int64
+}
+
+func LoadConfig() (*Config, error) {
+
+	// Unlike the other services ingestion can run from the environment
+	// alone, so a missing .env file is not an error.
+	err := godotenv.Load()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	config := &Config{
+		SERVER: ServerConfig{
+			Addr:     getEnv("INGEST_ADDR", ":8092"),
+			GRPCAddr: getEnv("INGEST_GRPC_ADDR", ":8093"),
+		},
+
+		DB: PostgresConfig{
+			Host:     os.Getenv("DB_HOST"),
+			Username: os.Getenv("DB_USERNAME"),
+			Password: os.Getenv("DB_PASSWORD"),
+			Url:      os.Getenv("DB_URL"),
+			Port:     os.Getenv("DB_PORT"),
+			Dbname:   os.Getenv("DB_NAME"),
+		},
+
+		KAFKA: KafkaConfig{
+			Brokers: strings.Split(getEnv("KAFKA_BROKERS", "localhost:9092"), ","),
+			Topic:   getEnv("KAFKA_TOPIC", "beacon-events"),
+		},
+
+		WAL: WALConfig{
+			Dir: getEnv("WAL_DIR", "data/wal"),
+		},
+
+		ENRICH: EnrichConfig{
+			GeoIPDatabase: os.Getenv("GEOIP_DB"),
+		},
+
+		BLOBS: BlobConfig{
+			Store: getEnv("BLOB_STORE", "local"),
+			Dir:   getEnv("BLOB_DIR", "data/blobs"),
+		},
+	}
+
+	config.SERVER.PublishTimeout, err = getDuration("PUBLISH_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	config.SERVER.MaxInFlight, err = getInt("MAX_IN_FLIGHT", 1000)
+	if err != nil {
+		return nil, err
+	}
+	if config.SERVER.MaxInFlight <= 0 {
+		return nil, fmt.Errorf("MAX_IN_FLIGHT: must be positive, got %d", config.SERVER.MaxInFlight)
+	}
+
+	config.SERVER.ShutdownTimeout, err = getDuration("SHUTDOWN_TIMEOUT", 15*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		config.SERVER.TrustedProxies = strings.Split(proxies, ",")
+	}
+
+	config.SERVER.MaxEventBytes, err = getInt64("MAX_EVENT_BYTES", 1<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	config.AUTH.ProjectCacheTTL, err = getDuration("PROJECT_CACHE_TTL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	config.KAFKA.BatchSize, err = getInt("KAFKA_BATCH_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+
+	config.KAFKA.BatchTimeout, err = getDuration("KAFKA_BATCH_TIMEOUT", 10*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
+	config.KAFKA.DialTimeout, err = getDuration("KAFKA_DIAL_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.KAFKA.RequiredAcks.UnmarshalText([]byte(getEnv("KAFKA_REQUIRED_ACKS", "one")))
+	if err != nil {
+		return nil, fmt.Errorf("KAFKA_REQUIRED_ACKS: %w", err)
+	}
+
+	err = config.KAFKA.Compression.UnmarshalText([]byte(getEnv("KAFKA_COMPRESSION", "none")))
+	if err != nil {
+		return nil, fmt.Errorf("KAFKA_COMPRESSION: %w", err)
+	}
+
+	config.DEDUP.Window, err = getDuration("EVENT_DEDUP_WINDOW", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	config.WAL.MaxBytes, err = getInt64("WAL_MAX_BYTES", 1<<30)
+	if err != nil {
+		return nil, err
+	}
+
+	config.WAL.SegmentBytes, err = getInt64("WAL_SEGMENT_BYTES", 64<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	config.WAL.MaxReplayBackoff, err = getDuration("WAL_MAX_REPLAY_BACKOFF", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	config.FILTER.CacheTTL, err = getDuration("FILTER_CACHE_TTL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	config.FILTER.FlushInterval, err = getDuration("FILTER_FLUSH_INTERVAL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	config.BLOBS.MaxUploadBytes, err = getInt64("MAX_ATTACHMENT_BYTES", 20<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	config.BLOBS.DefaultQuotaBytes, err = getInt64("ATTACHMENT_QUOTA_BYTES", 1<<30)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return n, nil
+}
+
+func getInt64(key string, fallback int64) (int64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return n, nil
+}
+
+func getDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return d, nil
+}
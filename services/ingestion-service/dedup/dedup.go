@@ -0,0 +1,62 @@
+package dedup
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache remembers accepted event IDs for a fixed window so that a retried
+// delivery can be acknowledged without being published a second time.
+type Cache struct {
+	mu        sync.Mutex
+	window    time.Duration
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewCache(window time.Duration) *Cache {
+	return &Cache{
+		window:    window,
+		seen:      make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+func key(projectID, eventID string) string {
+	return projectID + ":" + eventID
+}
+
+// Reserve records an event as accepted and reports true, or reports false
+// if it was already accepted within the window. Checking and recording in
+// one step keeps concurrent retries of an event from both being published.
+func (c *Cache) Reserve(projectID, eventID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	k := key(projectID, eventID)
+	if at, ok := c.seen[k]; ok && now.Sub(at) < c.window {
+		return false
+	}
+	c.seen[k] = now
+
+	if now.Sub(c.lastSweep) >= c.window/2 {
+		for k, at := range c.seen {
+			if now.Sub(at) >= c.window {
+				delete(c.seen, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	return true
+}
+
+// Release forgets a reservation whose event could not be handed off, so a
+// retry is accepted.
+func (c *Cache) Release(projectID, eventID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.seen, key(projectID, eventID))
+}
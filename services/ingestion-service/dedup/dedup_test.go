@@ -0,0 +1,76 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReserve(t *testing.T) {
+	type reserve struct {
+		projectID string
+		eventID   string
+		release   bool
+		want      bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []reserve
+	}{
+		{
+			name: "first delivery is accepted",
+			steps: []reserve{
+				{projectID: "p1", eventID: "e1", want: true},
+			},
+		},
+		{
+			name: "retry is rejected",
+			steps: []reserve{
+				{projectID: "p1", eventID: "e1", want: true},
+				{projectID: "p1", eventID: "e1", want: false},
+			},
+		},
+		{
+			name: "same ID in another project is accepted",
+			steps: []reserve{
+				{projectID: "p1", eventID: "e1", want: true},
+				{projectID: "p2", eventID: "e1", want: true},
+			},
+		},
+		{
+			name: "released reservation is accepted again",
+			steps: []reserve{
+				{projectID: "p1", eventID: "e1", want: true},
+				{projectID: "p1", eventID: "e1", release: true},
+				{projectID: "p1", eventID: "e1", want: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache(time.Minute)
+			for i, step := range tt.steps {
+				if step.release {
+					c.Release(step.projectID, step.eventID)
+					continue
+				}
+				if got := c.Reserve(step.projectID, step.eventID); got != step.want {
+					t.Fatalf("step %d: Reserve(%q, %q) = %v, want %v", i, step.projectID, step.eventID, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReserveExpires(t *testing.T) {
+	c := NewCache(10 * time.Millisecond)
+
+	if !c.Reserve("p1", "e1") {
+		t.Fatal("first Reserve = false, want true")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !c.Reserve("p1", "e1") {
+		t.Fatal("Reserve after the window = false, want true")
+	}
+}
@@ -0,0 +1,165 @@
+package enrich
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Client describes who delivered an event, as seen by ingestion. It travels
+// in the Kafka envelope next to the event.
+type Client struct {
+	IP             string `json:"ip,omitempty"`
+	Country        string `json:"country,omitempty"`
+	UserAgent      string `json:"user_agent,omitempty"`
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	Device         string `json:"device,omitempty"`
+	SDKName        string `json:"sdk_name,omitempty"`
+	SDKVersion     string `json:"sdk_version,omitempty"`
+}
+
+// Enricher builds Client records. Country lookup is only done when a GeoIP
+// database was configured.
+type Enricher struct {
+	geo *maxminddb.Reader
+}
+
+// New opens the MaxMind-format country or city database at geoIPPath. An
+// empty path disables country lookup.
+func New(geoIPPath string) (*Enricher, error) {
+	if geoIPPath == "" {
+		return &Enricher{}, nil
+	}
+
+	geo, err := maxminddb.Open(geoIPPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Enricher{geo: geo}, nil
+}
+
+func (e *Enricher) Close() error {
+	if e.geo == nil {
+		return nil
+	}
+	return e.geo.Close()
+}
+
+// Client describes the sender of a request from its address, User-Agent
+// header and SDK identifier ("name/version").
+func (e *Enricher) Client(ip, userAgent, sdk string) *Client {
+	client := &Client{
+		IP:        ip,
+		UserAgent: userAgent,
+		Country:   e.country(ip),
+	}
+
+	client.SDKName, client.SDKVersion = ParseSDK(sdk)
+	client.parseUserAgent(userAgent)
+
+	return client
+}
+
+func (e *Enricher) country(ip string) string {
+	if e.geo == nil {
+		return ""
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	err := e.geo.Lookup(addr, &record)
+	if err != nil {
+		return ""
+	}
+	return record.Country.ISOCode
+}
+
+// ParseSDK splits an SDK identifier such as "beacon-go/0.2.0" or
+// "sentry.python/1.40.0".
+func ParseSDK(sdk string) (name, version string) {
+	sdk = strings.TrimSpace(sdk)
+	if i := strings.IndexByte(sdk, ' '); i >= 0 {
+		sdk = sdk[:i]
+	}
+	name, version, _ = strings.Cut(sdk, "/")
+	return name, version
+}
+
+var (
+	browsers = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		// Order matters: Edge and Opera also claim to be Chrome, and Chrome
+		// claims to be Safari.
+		{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+		{"Opera", regexp.MustCompile(`OPR/([\d.]+)`)},
+		{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+		{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+		{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+		{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+	}
+
+	systems = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"iOS", regexp.MustCompile(`(?:iPhone|CPU) OS ([\d_]+)`)},
+		{"Android", regexp.MustCompile(`Android ([\d.]+)`)},
+		{"Windows", regexp.MustCompile(`Windows NT ([\d.]+)`)},
+		{"macOS", regexp.MustCompile(`Mac OS X ([\d_.]+)`)},
+		{"Chrome OS", regexp.MustCompile(`CrOS \S+ ([\d.]+)`)},
+		{"Linux", regexp.MustCompile(`Linux()`)},
+	}
+
+	// Non-browser clients usually lead with "product/version".
+	product = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)`)
+)
+
+func (c *Client) parseUserAgent(ua string) {
+	if ua == "" {
+		return
+	}
+
+	for _, b := range browsers {
+		if m := b.re.FindStringSubmatch(ua); m != nil {
+			c.Browser, c.BrowserVersion = b.name, m[1]
+			break
+		}
+	}
+
+	for _, s := range systems {
+		if m := s.re.FindStringSubmatch(ua); m != nil {
+			c.OS, c.OSVersion = s.name, strings.ReplaceAll(m[1], "_", ".")
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet"):
+		c.Device = "tablet"
+	case strings.Contains(ua, "Mobi"):
+		c.Device = "mobile"
+	case c.Browser != "":
+		c.Device = "desktop"
+	}
+
+	if c.Browser == "" && !strings.HasPrefix(ua, "Mozilla/") {
+		if m := product.FindStringSubmatch(ua); m != nil {
+			c.Browser, c.BrowserVersion = m[1], m[2]
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package filter
+
+import (
+	"context"
+	"log"
+	"net"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/models"
+	"gorm.io/gorm"
+)
+
+// Event is the part of an event inbound filters look at.
+type Event struct {
+	Message     string
+	Level       string
+	Environment string
+	Release     string
+	ClientIP    string
+}
+
+type rule struct {
+	id      string
+	kind    string
+	pattern string
+	regex   *regexp.Regexp
+	network *net.IPNet
+}
+
+func compile(f models.InboundFilter) (rule, bool) {
+	r := rule{id: f.ID, kind: f.Type, pattern: f.Pattern}
+
+	switch f.Type {
+	case "message":
+		regex, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return r, false
+		}
+		r.regex = regex
+	case "ip":
+		_, network, err := net.ParseCIDR(f.Pattern)
+		if err != nil {
+			return r, false
+		}
+		r.network = network
+	case "level", "environment", "release":
+	default:
+		return r, false
+	}
+
+	return r, true
+}
+
+func glob(pattern, value string) bool {
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+func (r rule) matches(e Event) bool {
+	switch r.kind {
+	case "message":
+		return r.regex.MatchString(e.Message)
+	case "level":
+		return strings.EqualFold(r.pattern, e.Level)
+	case "environment":
+		return glob(r.pattern, e.Environment)
+	case "release":
+		return glob(r.pattern, e.Release)
+	case "ip":
+		ip := net.ParseIP(e.ClientIP)
+		return ip != nil && r.network.Contains(ip)
+	}
+	return false
+}
+
+type cachedRules struct {
+	rules     []rule
+	expiresAt time.Time
+}
+
+// Engine evaluates the inbound filters configured in auth-service. Rules are
+// cached per project for a TTL, and drop counts are accumulated in memory and
+// added to inbound_filters.dropped_count by Run.
+type Engine struct {
+	DB  *gorm.DB
+	TTL time.Duration
+
+	mu      sync.Mutex
+	cache   map[string]cachedRules
+	dropped map[string]int64
+	lastHit map[string]time.Time
+}
+
+func NewEngine(db *gorm.DB, ttl time.Duration) *Engine {
+	return &Engine{
+		DB:      db,
+		TTL:     ttl,
+		cache:   make(map[string]cachedRules),
+		dropped: make(map[string]int64),
+		lastHit: make(map[string]time.Time),
+	}
+}
+
+func (e *Engine) rules(projectID string) ([]rule, error) {
+	e.mu.Lock()
+	entry, ok := e.cache[projectID]
+	e.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.rules, nil
+	}
+
+	var filters []models.InboundFilter
+	err := e.DB.Where("project_id = ? AND enabled", projectID).Find(&filters).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]rule, 0, len(filters))
+	for _, f := range filters {
+		r, ok := compile(f)
+		if !ok {
+			log.Printf("Skipping invalid inbound filter %s (%s %q)", f.ID, f.Type, f.Pattern)
+			continue
+		}
+		rules = append(rules, r)
+	}
+
+	e.mu.Lock()
+	e.cache[projectID] = cachedRules{rules: rules, expiresAt: time.Now().Add(e.TTL)}
+	e.mu.Unlock()
+
+	return rules, nil
+}
+
+// Match returns the ID of the first filter of the project that matches the
+// event, or "" when the event should be kept.
+func (e *Engine) Match(projectID string, event Event) (string, error) {
+	rules, err := e.rules(projectID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, r := range rules {
+		if r.matches(event) {
+			e.mu.Lock()
+			e.dropped[r.id]++
+			e.lastHit[r.id] = time.Now()
+			e.mu.Unlock()
+			return r.id, nil
+		}
+	}
+
+	return "", nil
+}
+
+// Flush adds the drop counts accumulated since the last flush to the
+// database. Counts that fail to write are kept for the next attempt.
+func (e *Engine) Flush() {
+	e.mu.Lock()
+	dropped, lastHit := e.dropped, e.lastHit
+	e.dropped = make(map[string]int64)
+	e.lastHit = make(map[string]time.Time)
+	e.mu.Unlock()
+
+	for id, n := range dropped {
+		err := e.DB.Table("inbound_filters").Where("id = ?", id).Updates(map[string]interface{}{
+			"dropped_count":   gorm.Expr("dropped_count + ?", n),
+			"last_dropped_at": lastHit[id],
+		}).Error
+		if err == nil {
+			continue
+		}
+
+		log.Println("Failed to record inbound filter drops:", err)
+
+		e.mu.Lock()
+		e.dropped[id] += n
+		if e.lastHit[id].Before(lastHit[id]) {
+			e.lastHit[id] = lastHit[id]
+		}
+		e.mu.Unlock()
+	}
+}
+
+// Run flushes drop counts every interval until ctx is done, then once more.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.Flush()
+			return
+		case <-ticker.C:
+			e.Flush()
+		}
+	}
+}
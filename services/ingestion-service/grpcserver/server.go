@@ -0,0 +1,212 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/k1ngalph0x/beacon/sdk/ingestpb"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/enrich"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/handler"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/middleware"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/models"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/wal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the gRPC ingestion API on top of the same pipeline as
+// the HTTP routes: secret-key auth, then handler.Accept for every event.
+type Server struct {
+	ingestpb.UnimplementedIngestServiceServer
+
+	Handler *handler.Handler
+	Auth    *middleware.ProjectAuth
+}
+
+func NewServer(h *handler.Handler, auth *middleware.ProjectAuth) *Server {
+	return &Server{Handler: h, Auth: auth}
+}
+
+// Register creates a grpc.Server serving the ingestion API. Messages are
+// held to the same size limit as HTTP event bodies, and calls to the same
+// in-flight limit as HTTP requests.
+func Register(s *Server, maxMessageBytes int64, limiter *middleware.Limiter) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.MaxRecvMsgSize(int(maxMessageBytes)),
+		grpc.UnaryInterceptor(limitUnary(limiter)),
+		grpc.StreamInterceptor(limitStream(limiter)),
+	)
+	ingestpb.RegisterIngestServiceServer(server, s)
+	return server
+}
+
+var errTooManyCalls = status.Error(codes.ResourceExhausted, "Too many in-flight requests")
+
+// limitUnary rejects calls while every in-flight slot is taken, rather than
+// queueing them.
+func limitUnary(limiter *middleware.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !limiter.Acquire() {
+			return nil, errTooManyCalls
+		}
+		defer limiter.Release()
+		return handler(ctx, req)
+	}
+}
+
+// limitStream holds an in-flight slot for the lifetime of a stream.
+func limitStream(limiter *middleware.Limiter) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !limiter.Acquire() {
+			return errTooManyCalls
+		}
+		defer limiter.Release()
+		return handler(srv, stream)
+	}
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// authenticate resolves the "authorization: Bearer <secret key>" metadata to
+// a project and describes the calling client.
+func (s *Server) authenticate(ctx context.Context) (*models.Project, *enrich.Client, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	parts := strings.Split(strings.TrimSpace(firstMetadata(md, "authorization")), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	project, err := s.Auth.BySecretKey(parts[1])
+	if err != nil {
+		return nil, nil, status.Error(codes.Internal, "Internal server error")
+	}
+	if project == nil {
+		return nil, nil, status.Error(codes.Unauthenticated, "Invalid project key")
+	}
+	if !project.IsActive {
+		return nil, nil, status.Error(codes.PermissionDenied, "Project is disabled")
+	}
+
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+
+	client := s.Handler.Enricher.Client(ip, firstMetadata(md, "user-agent"), firstMetadata(md, "x-beacon-sdk"))
+	return project, client, nil
+}
+
+func fromProto(e *ingestpb.Event) handler.Event {
+	event := handler.Event{
+		EventID:     e.GetEventId(),
+		ProjectID:   e.GetProjectId(),
+		Level:       e.GetLevel(),
+		Message:     e.GetMessage(),
+		StackTrace:  e.GetStackTrace(),
+		Environment: e.GetEnvironment(),
+		Release:     e.GetRelease(),
+		Tags:        e.GetTags(),
+		TraceID:     e.GetTraceId(),
+		SpanID:      e.GetSpanId(),
+		Fingerprint: e.GetFingerprint(),
+	}
+
+	if e.GetTimestamp() != nil {
+		event.Timestamp = e.GetTimestamp().AsTime()
+	}
+
+	if u := e.GetUser(); u != nil {
+		event.User = &handler.User{
+			ID:        u.GetId(),
+			Email:     u.GetEmail(),
+			Username:  u.GetUsername(),
+			IPAddress: u.GetIpAddress(),
+		}
+	}
+
+	return event
+}
+
+// accept runs one event through handler.Accept. Events the client has to fix
+// come back as a "rejected" result; anything else is returned as a gRPC
+// error.
+func (s *Server) accept(ctx context.Context, project *models.Project, client *enrich.Client, req *ingestpb.SendEventRequest) (*ingestpb.SendEventResponse, error) {
+	if req.GetEvent() == nil {
+		return &ingestpb.SendEventResponse{Status: "rejected", Error: "event is required"}, nil
+	}
+
+	event := fromProto(req.GetEvent())
+
+	result, err := s.Handler.Accept(ctx, project, &event, client)
+
+	var badEvent *handler.BadEventError
+	switch {
+	case errors.As(err, &badEvent):
+		return &ingestpb.SendEventResponse{EventId: event.EventID, Status: "rejected", Error: badEvent.Message}, nil
+	case errors.Is(err, wal.ErrFull):
+		return nil, status.Error(codes.ResourceExhausted, "Event buffer is full")
+	case err != nil:
+		log.Println("Failed to accept event:", err)
+		return nil, status.Error(codes.Unavailable, "Failed to publish to kafka")
+	}
+
+	return &ingestpb.SendEventResponse{EventId: event.EventID, Status: result}, nil
+}
+
+func (s *Server) SendEvent(ctx context.Context, req *ingestpb.SendEventRequest) (*ingestpb.SendEventResponse, error) {
+	project, client, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.accept(ctx, project, client, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetStatus() == "rejected" {
+		return nil, status.Error(codes.InvalidArgument, resp.GetError())
+	}
+	return resp, nil
+}
+
+func (s *Server) SendEvents(stream ingestpb.IngestService_SendEventsServer) error {
+	ctx := stream.Context()
+
+	project, client, err := s.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	var results []*ingestpb.SendEventResponse
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&ingestpb.SendEventsResponse{Results: results})
+		}
+		if err != nil {
+			return err
+		}
+
+		resp, err := s.accept(ctx, project, client, req)
+		if err != nil {
+			return err
+		}
+		results = append(results, resp)
+	}
+}
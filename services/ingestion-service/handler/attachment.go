@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/middleware"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/models"
+)
+
+var errQuotaExceeded = errors.New("attachment quota exceeded")
+
+// clientReader remembers read errors, which are the client's fault (a
+// truncated or oversized body), so they can be told apart from blob store
+// failures.
+type clientReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *clientReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+type clientError struct {
+	err error
+}
+
+func (e *clientError) Error() string { return e.err.Error() }
+func (e *clientError) Unwrap() error { return e.err }
+
+// attachmentQuota returns the storage quota of a project in bytes.
+func (h *Handler) attachmentQuota(project *models.Project) int64 {
+	if project.AttachmentQuotaBytes > 0 {
+		return project.AttachmentQuotaBytes
+	}
+	return h.Config.BLOBS.DefaultQuotaBytes
+}
+
+func (h *Handler) attachmentUsage(projectID string) (int64, error) {
+	var used int64
+	err := h.DB.Model(&models.Attachment{}).
+		Where("project_id = ?", projectID).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&used).Error
+	return used, err
+}
+
+func (h *Handler) deleteBlobs(ctx context.Context, attachments []models.Attachment) {
+	for _, a := range attachments {
+		err := h.Blobs.Delete(ctx, a.BlobKey)
+		if err != nil {
+			log.Println("Failed to delete attachment blob:", err)
+		}
+	}
+}
+
+// storeAttachments streams every file part of a multipart body into the
+// blob store, stopping once the project's quota would be exceeded.
+func (h *Handler) storeAttachments(ctx context.Context, project *models.Project, eventID string, reader *multipart.Reader, used int64) ([]models.Attachment, error) {
+	var stored []models.Attachment
+	quota := h.attachmentQuota(project)
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return stored, nil
+		}
+		if err != nil {
+			return stored, &clientError{err: err}
+		}
+
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		attachment := models.Attachment{
+			ID:          uuid.New().String(),
+			ProjectID:   project.ID,
+			EventID:     eventID,
+			Type:        part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+		}
+		if attachment.Type == "" {
+			attachment.Type = "attachment"
+		}
+		if attachment.ContentType == "" {
+			attachment.ContentType = "application/octet-stream"
+		}
+		attachment.BlobKey = fmt.Sprintf("%s/%s/%s", project.ID, eventID, attachment.ID)
+
+		// The blob goes in before the quota check because the part size is
+		// only known once it has been read.
+		body := &clientReader{r: part}
+		attachment.Size, err = h.Blobs.Put(ctx, attachment.BlobKey, body)
+		part.Close()
+		if body.err != nil {
+			return stored, &clientError{err: body.err}
+		}
+		if err != nil {
+			return stored, err
+		}
+
+		stored = append(stored, attachment)
+
+		used += attachment.Size
+		if used > quota {
+			return stored, errQuotaExceeded
+		}
+	}
+}
+
+// UploadAttachments stores the files of a multipart/form-data request and
+// links them to an event. The form field name becomes the attachment type,
+// e.g. "log", "goroutine_dump" or "minidump". The event does not have to have
+// arrived yet, so SDKs may upload before or after sending it.
+func (h *Handler) UploadAttachments(c *gin.Context) {
+	project := middleware.CurrentProject(c)
+
+	eventID, err := uuid.Parse(c.Param("event_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event_id"})
+		return
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected a multipart/form-data body"})
+		return
+	}
+
+	used, err := h.attachmentUsage(project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Concurrent uploads can overshoot the quota by at most one request each.
+	if used >= h.attachmentQuota(project) {
+		c.JSON(http.StatusInsufficientStorage, gin.H{"error": "Attachment storage quota exceeded"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	attachments, err := h.storeAttachments(ctx, project, eventID.String(), reader, used)
+	if err != nil {
+		h.deleteBlobs(context.WithoutCancel(ctx), attachments)
+
+		var bad *clientError
+		switch {
+		case errors.Is(err, errQuotaExceeded):
+			c.JSON(http.StatusInsufficientStorage, gin.H{"error": "Attachment storage quota exceeded"})
+		case errors.As(err, &bad):
+			badBody(c, err, "Invalid multipart body")
+		default:
+			log.Println("Failed to store attachment:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store attachment"})
+		}
+		return
+	}
+
+	if len(attachments) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No files in request"})
+		return
+	}
+
+	err = h.DB.Create(&attachments).Error
+	if err != nil {
+		h.deleteBlobs(context.WithoutCancel(ctx), attachments)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save attachments"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"event_id":    eventID.String(),
+		"attachments": attachments,
+	})
+}
@@ -1,51 +1,304 @@
 package handler
 
 import (
+	"compress/flate"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/blobstore"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/config"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/dedup"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/enrich"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/filter"
 	"github.com/k1ngalph0x/beacon/services/ingestion-service/kafka"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/metrics"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/middleware"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/models"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/wal"
+	"gorm.io/gorm"
 )
 
+type Handler struct {
+	Config *config.Config
+	// Retries from the SDK arrive within seconds, so a short window here is
+	// enough; kafka-service and issue-service de-duplicate over longer periods.
+	SeenEvents *dedup.Cache
+	// Events land here when Kafka cannot take them and are replayed in order.
+	WAL *wal.WAL
+	// Attachment metadata is written to DB, the content to Blobs.
+	DB    *gorm.DB
+	Blobs blobstore.Store
+	// Per-project inbound filters that drop known noise before publishing.
+	Filters *filter.Engine
+	// Describes the client that delivered each event.
+	Enricher *enrich.Enricher
+}
+
+var filteredEvents = metrics.NewCounterVec("beacon_filtered_events_total", "Events dropped by inbound filters.", "project_id", "filter_id")
+
 type Event struct{
-	ProjectID  string    `json:"project_id"`
-	Timestamp  time.Time `json:"timestamp"`
-	Level      string    `json:"level"`
-	Message    string    `json:"message"`
-	StackTrace string    `json:"stack_trace"`
+	EventID     string            `json:"event_id"`
+	ProjectID   string            `json:"project_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"`
+	Message     string            `json:"message"`
+	StackTrace  string            `json:"stack_trace"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	User        *User             `json:"user,omitempty"`
+	TraceID     string            `json:"trace_id,omitempty"`
+	SpanID      string            `json:"span_id,omitempty"`
+	// Client-chosen grouping; "{{ default }}" stands for issue-service's own
+	// fingerprint.
+	Fingerprint []string          `json:"fingerprint,omitempty"`
+	// Set by ingestion from the request; anything the client sends is replaced.
+	Client      *enrich.Client    `json:"client,omitempty"`
 }
 
-func Ingest(c *gin.Context){
-	var event Event
+type User struct {
+	ID        string `json:"id,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Username  string `json:"username,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+}
 
-	err := c.ShouldBind(&event)
+func NewHandler(cfg *config.Config, buffer *wal.WAL, db *gorm.DB, blobs blobstore.Store, enricher *enrich.Enricher) *Handler {
+	return &Handler{
+		Config:     cfg,
+		SeenEvents: dedup.NewCache(cfg.DEDUP.Window),
+		WAL:        buffer,
+		DB:         db,
+		Blobs:      blobs,
+		Filters:    filter.NewEngine(db, cfg.FILTER.CacheTTL),
+		Enricher:   enricher,
+	}
+}
 
-	println("event")
-	println("Project", event.ProjectID)
-	println("Level", event.Level)
-	println("Message", event.Message)
+// publish hands the event to Kafka, falling back to the write-ahead log when
+// the broker fails or is too slow. While anything is buffered new events are
+// appended behind it, so the replayer delivers them in order.
+func (h *Handler) publish(ctx context.Context, projectID string, payload []byte) error {
+	if h.WAL.Depth() == 0 {
+		err := kafka.Publish(ctx, projectID, payload)
+		if err == nil {
+			return nil
+		}
+		log.Println("Kafka publish failed, buffering event:", err)
+	}
 
-	if err != nil{
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event payload"})
-		return
+	return h.WAL.Append(wal.Record{Key: []byte(projectID), Value: payload})
+}
+
+// Bounds on client-supplied fingerprints, which issue-service hashes.
+const (
+	maxFingerprintParts   = 32
+	maxFingerprintPartLen = 512
+)
+
+// BadEventError is returned by Accept for events the client has to fix.
+type BadEventError struct {
+	Message string
+}
+
+func (e *BadEventError) Error() string {
+	return e.Message
+}
+
+// Accept runs an event through the pipeline shared by every ingestion route:
+// it assigns the event to the authenticated project, normalises the event ID,
+// attaches the client description, drops recent duplicates, applies inbound
+// filters and publishes. It returns "queued", "filtered" or "duplicate".
+func (h *Handler) Accept(ctx context.Context, project *models.Project, event *Event, client *enrich.Client) (string, error) {
+	if event.ProjectID != "" && event.ProjectID != project.ID {
+		return "", &BadEventError{Message: "project_id does not match the project key"}
+	}
+	event.ProjectID = project.ID
+	event.Client = client
+
+	if event.EventID == "" {
+		event.EventID = uuid.New().String()
+	} else {
+		id, err := uuid.Parse(event.EventID)
+		if err != nil {
+			return "", &BadEventError{Message: "Invalid event_id"}
+		}
+		event.EventID = id.String()
+	}
+
+	if len(event.Fingerprint) > maxFingerprintParts {
+		return "", &BadEventError{Message: "fingerprint has too many entries"}
+	}
+	for _, part := range event.Fingerprint {
+		if len(part) > maxFingerprintPartLen {
+			return "", &BadEventError{Message: "fingerprint entry is too long"}
+		}
+	}
+
+	// Duplicates are dropped before filtering, so retries of a filtered
+	// event do not count towards the filter again. A filtered event keeps
+	// its reservation.
+	if !h.SeenEvents.Reserve(event.ProjectID, event.EventID) {
+		return "duplicate", nil
+	}
+
+	// A filter lookup failure lets the event through; losing real errors is
+	// worse than letting some noise in.
+	filterID, err := h.Filters.Match(project.ID, filter.Event{
+		Message:     event.Message,
+		Level:       event.Level,
+		Environment: event.Environment,
+		Release:     event.Release,
+		ClientIP:    client.IP,
+	})
+	if err != nil {
+		log.Println("Failed to load inbound filters:", err)
+	}
+	if filterID != "" {
+		filteredEvents.Inc(project.ID, filterID)
+		return "filtered", nil
 	}
 
 	payload, err := json.Marshal(event)
 	if err != nil{
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		return 
+		h.SeenEvents.Release(event.ProjectID, event.EventID)
+		return "", err
 	}
 
-	err  = kafka.Publish(event.ProjectID, payload)
+	ctx, cancel := context.WithTimeout(ctx, h.Config.SERVER.PublishTimeout)
+	defer cancel()
+
+	err = h.publish(ctx, event.ProjectID, payload)
 	if err != nil{
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish to kafka"})
+		h.SeenEvents.Release(event.ProjectID, event.EventID)
+		return "", err
+	}
+
+	return "queued", nil
+}
+
+func (h *Handler) respond(c *gin.Context, event *Event, status string, err error) {
+	var badEvent *BadEventError
+
+	switch {
+	case errors.As(err, &badEvent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": badEvent.Message})
+	case errors.Is(err, wal.ErrFull):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Event buffer is full"})
+	case err != nil:
+		log.Println("Failed to accept event:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish to kafka"})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{
+			"status":   status,
+			"event_id": event.EventID,
+		})
+	}
+}
+
+func (h *Handler) Ingest(c *gin.Context){
+	var event Event
+
+	err := c.ShouldBind(&event)
+	if err != nil{
+		badBody(c, err, "Invalid event payload")
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"status": "queued",
-	})
-	
-}
\ No newline at end of file
+	status, err := h.Accept(c.Request.Context(), middleware.CurrentProject(c), &event, h.client(c, c.GetHeader("X-Beacon-SDK")))
+	h.respond(c, &event, status, err)
+}
+
+// client describes the sender of the current request. sdk identifies the
+// reporting SDK as "name/version"; where it comes from depends on the route.
+func (h *Handler) client(c *gin.Context, sdk string) *enrich.Client {
+	return h.Enricher.Client(c.ClientIP(), c.GetHeader("User-Agent"), sdk)
+}
+
+// exceptionMessage formats an exception as "Type: value", the message shape
+// used for events translated from other protocols.
+func exceptionMessage(exceptionType, value string) string {
+	switch {
+	case exceptionType != "" && value != "":
+		return exceptionType + ": " + value
+	case exceptionType != "":
+		return exceptionType
+	default:
+		return value
+	}
+}
+
+// badBody rejects a body that could not be read or decoded, answering 413
+// when it was cut off by the size limit.
+func badBody(c *gin.Context, err error, message string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body exceeds the %d byte limit", tooLarge.Limit)})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": message})
+}
+
+// readBody returns the request body, undoing any gzip or deflate
+// Content-Encoding. Sentry SDKs and OTLP exporters commonly compress. The
+// decompressed size is held to the same limit as the raw body, so a small
+// compressed payload cannot expand without bound.
+func (h *Handler) readBody(c *gin.Context) ([]byte, error) {
+	var r io.Reader = c.Request.Body
+
+	switch strings.ToLower(c.GetHeader("Content-Encoding")) {
+	case "gzip":
+		gz, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	case "deflate":
+		fl := flate.NewReader(c.Request.Body)
+		defer fl.Close()
+		r = fl
+	}
+
+	limit := h.Config.SERVER.MaxEventBytes
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, &http.MaxBytesError{Limit: limit}
+	}
+	return body, nil
+}
+
+// IngestBrowser accepts events reported from web pages. navigator.sendBeacon
+// cannot send application/json, so the body is decoded as JSON whatever its
+// declared content type.
+func (h *Handler) IngestBrowser(c *gin.Context) {
+	var event Event
+
+	body, err := h.readBody(c)
+	if err != nil {
+		badBody(c, err, "Invalid event payload")
+		return
+	}
+
+	err = json.Unmarshal(body, &event)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event payload"})
+		return
+	}
+
+	status, err := h.Accept(c.Request.Context(), middleware.CurrentProject(c), &event, h.client(c, c.GetHeader("X-Beacon-SDK")))
+	h.respond(c, &event, status, err)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/middleware"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/otlp"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpNamespace seeds the deterministic event IDs of OTLP records.
+var otlpNamespace = uuid.MustParse("5d3c6a1e-2f4b-4c1a-9a3e-7b8e0d6f4c21")
+
+func fromOTLP(r otlp.Record) Event {
+	event := Event{
+		Timestamp:   r.Timestamp,
+		Level:       r.Level,
+		Message:     exceptionMessage(r.ExceptionType, r.Message),
+		StackTrace:  r.StackTrace,
+		Environment: r.Environment,
+		Release:     r.Release,
+		Tags:        r.Tags,
+		TraceID:     r.TraceID,
+		SpanID:      r.SpanID,
+	}
+
+	// OTLP has no event ID. Deriving one from the payload means an exporter
+	// retrying a batch is de-duplicated like an SDK retry, so it must not
+	// depend on when the record was received. Without a time in the payload
+	// repeats of the same error would share an ID and be dropped, so such
+	// records get a random one.
+	if r.TimeUnixNano == 0 {
+		event.EventID = uuid.New().String()
+		return event
+	}
+	key := fmt.Sprintf("%s|%s|%d|%s|%s", r.TraceID, r.SpanID, r.TimeUnixNano, event.Message, r.StackTrace)
+	event.EventID = uuid.NewSHA1(otlpNamespace, []byte(key)).String()
+
+	return event
+}
+
+func (h *Handler) acceptOTLP(c *gin.Context, records []otlp.Record, response proto.Message) {
+	project := middleware.CurrentProject(c)
+	// Exporters identify themselves in the User-Agent, e.g.
+	// "OTel-OTLP-Exporter-Go/1.28.0".
+	client := h.client(c, c.GetHeader("User-Agent"))
+
+	for _, r := range records {
+		event := fromOTLP(r)
+
+		status, err := h.Accept(c.Request.Context(), project, &event, client)
+		if err != nil {
+			h.respond(c, &event, status, err)
+			return
+		}
+	}
+
+	contentType := c.ContentType()
+	body, err := otlp.Encode(response, contentType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if !otlp.IsJSON(contentType) {
+		contentType = "application/x-protobuf"
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// OTLPLogs implements the OTLP/HTTP logs endpoint. Records at ERROR severity
+// or above become Beacon events; everything else is acknowledged and dropped.
+func (h *Handler) OTLPLogs(c *gin.Context) {
+	body, err := h.readBody(c)
+	if err != nil {
+		badBody(c, err, "Invalid OTLP payload")
+		return
+	}
+
+	req, err := otlp.DecodeLogs(body, c.ContentType())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OTLP payload"})
+		return
+	}
+
+	h.acceptOTLP(c, otlp.ErrorsFromLogs(req), &collogspb.ExportLogsServiceResponse{})
+}
+
+// OTLPTraces implements the OTLP/HTTP traces endpoint. Span "exception"
+// events become Beacon events carrying the trace and span IDs.
+func (h *Handler) OTLPTraces(c *gin.Context) {
+	body, err := h.readBody(c)
+	if err != nil {
+		badBody(c, err, "Invalid OTLP payload")
+		return
+	}
+
+	req, err := otlp.DecodeTraces(body, c.ContentType())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OTLP payload"})
+		return
+	}
+
+	h.acceptOTLP(c, otlp.ErrorsFromTraces(req), &coltracepb.ExportTraceServiceResponse{})
+}
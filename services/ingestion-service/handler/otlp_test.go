@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/otlp"
+)
+
+func TestFromOTLPEventID(t *testing.T) {
+	record := otlp.Record{TraceID: "01", SpanID: "02", TimeUnixNano: 42, Message: "boom"}
+	other := record
+	other.TimeUnixNano = 43
+	untimed := record
+	untimed.TimeUnixNano = 0
+
+	tests := []struct {
+		name     string
+		a, b     otlp.Record
+		wantSame bool
+	}{
+		{name: "retried record", a: record, b: record, wantSame: true},
+		{name: "record at another time", a: record, b: other, wantSame: false},
+		{name: "repeated record without a time", a: untimed, b: untimed, wantSame: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := fromOTLP(tt.a).EventID, fromOTLP(tt.b).EventID
+			if a == "" || b == "" {
+				t.Fatalf("event IDs %q, %q must not be empty", a, b)
+			}
+			if (a == b) != tt.wantSame {
+				t.Errorf("event IDs %q, %q: same = %v, want %v", a, b, a == b, tt.wantSame)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/middleware"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/sentry"
+)
+
+// fromSentry translates a Sentry event into Beacon's envelope. The primary
+// exception (the last one in the chain) provides the message and stack trace.
+func fromSentry(se *sentry.Event) Event {
+	event := Event{
+		EventID:     se.EventID,
+		Timestamp:   time.Time(se.Timestamp),
+		Level:       se.Level,
+		Environment: se.Environment,
+		Release:     se.Release,
+		Tags:        se.Tags,
+		// Sentry uses the same "{{ default }}" placeholder.
+		Fingerprint: se.Fingerprint,
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	if event.Level == "" {
+		event.Level = "error"
+	}
+
+	if len(se.Exception) > 0 {
+		primary := se.Exception[len(se.Exception)-1]
+
+		event.Message = exceptionMessage(primary.Type, primary.Value)
+
+		if primary.Stacktrace != nil {
+			event.StackTrace = formatFrames(primary.Stacktrace.Frames)
+		}
+	}
+
+	if event.Message == "" && se.LogEntry != nil {
+		event.Message = se.LogEntry.String()
+	}
+	if event.Message == "" {
+		event.Message = se.Message.String()
+	}
+
+	if se.ServerName != "" || se.Logger != "" {
+		if event.Tags == nil {
+			event.Tags = map[string]string{}
+		}
+		if se.ServerName != "" {
+			event.Tags["server_name"] = se.ServerName
+		}
+		if se.Logger != "" {
+			event.Tags["logger"] = se.Logger
+		}
+	}
+
+	if se.User != nil {
+		event.User = &User{
+			ID:        se.User.ID,
+			Email:     se.User.Email,
+			Username:  se.User.Username,
+			IPAddress: se.User.IPAddress,
+		}
+	}
+
+	return event
+}
+
+// formatFrames renders Sentry frames, which are ordered outermost first, as
+// a Go-style trace with the innermost call first. The SDK's in-app flag
+// follows the file and line, where issue-service reads it back.
+func formatFrames(frames []sentry.Frame) string {
+	var sb strings.Builder
+
+	for i := len(frames) - 1; i >= 0; i-- {
+		f := frames[i]
+
+		function := f.Function
+		if function == "" {
+			function = "?"
+		}
+		if f.Module != "" {
+			function = f.Module + "." + function
+		}
+
+		file := f.AbsPath
+		if file == "" {
+			file = f.Filename
+		}
+
+		fmt.Fprintf(&sb, "%s\n\t%s:%d", function, file, f.Lineno)
+		if f.InApp != nil {
+			fmt.Fprintf(&sb, " in_app=%t", *f.InApp)
+		}
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+// sentryID formats an event ID the way Sentry SDKs expect it back: 32 hex
+// characters without dashes.
+func sentryID(eventID string) string {
+	return strings.ReplaceAll(eventID, "-", "")
+}
+
+func (h *Handler) acceptSentry(c *gin.Context, events []*sentry.Event) {
+	project := middleware.CurrentProject(c)
+	client := h.client(c, sentry.ClientFromRequest(c.Request))
+	response := gin.H{}
+
+	for i, se := range events {
+		event := fromSentry(se)
+
+		// Browser SDKs ask for the sender's address to be filled in.
+		if event.User != nil && event.User.IPAddress == "{{auto}}" {
+			event.User.IPAddress = client.IP
+		}
+
+		status, err := h.Accept(c.Request.Context(), project, &event, client)
+		if err != nil {
+			h.respond(c, &event, status, err)
+			return
+		}
+
+		if i == 0 {
+			response["id"] = sentryID(event.EventID)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SentryStore implements the legacy /api/:project_id/store/ endpoint, whose
+// body is a single event.
+func (h *Handler) SentryStore(c *gin.Context) {
+	var se sentry.Event
+
+	body, err := h.readBody(c)
+	if err != nil {
+		badBody(c, err, "Invalid event payload")
+		return
+	}
+
+	err = json.Unmarshal(body, &se)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event payload"})
+		return
+	}
+
+	h.acceptSentry(c, []*sentry.Event{&se})
+}
+
+// SentryEnvelope implements /api/:project_id/envelope/.
+func (h *Handler) SentryEnvelope(c *gin.Context) {
+	body, err := h.readBody(c)
+	if err != nil {
+		badBody(c, err, "Invalid envelope")
+		return
+	}
+
+	events, err := sentry.ParseEnvelope(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid envelope"})
+		return
+	}
+
+	h.acceptSentry(c, events)
+}
@@ -2,27 +2,78 @@ package kafka
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/config"
 	"github.com/segmentio/kafka-go"
 )
 
 var Writer *kafka.Writer
 
-func InitKafka() {
+// InitKafka creates the writer. An unreachable cluster does not stop the
+// service: it starts degraded and the write-ahead log buffers events until
+// the brokers recover.
+func InitKafka(cfg config.KafkaConfig) {
+	err := checkBrokers(cfg)
+	if err != nil {
+		log.Printf("Warning: %v; starting degraded, events are buffered until kafka recovers", err)
+	}
+
 	Writer = &kafka.Writer{
-		Addr: kafka.TCP("localhost:9092"),
-		Topic: "beacon-events",
-		Balancer: &kafka.LeastBytes{},
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafka.LeastBytes{},
+		BatchSize:    cfg.BatchSize,
+		BatchTimeout: cfg.BatchTimeout,
+		RequiredAcks: cfg.RequiredAcks,
+		Compression:  cfg.Compression,
 	}
-}	
+}
+
+// checkBrokers dials the configured brokers until one of them answers with
+// metadata for the topic, so that a misconfigured or unreachable cluster is
+// reported at startup rather than on the first event.
+func checkBrokers(cfg config.KafkaConfig) error {
+	var errs []error
 
+	for _, broker := range cfg.Brokers {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.DialTimeout)
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		cancel()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
 
-func Publish(projectID string, message []byte)error{
-	return Writer.WriteMessages(context.Background(),
+		_, err = conn.ReadPartitions(cfg.Topic)
+		conn.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", broker, err))
+			continue
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("kafka unreachable (brokers %v, topic %q): %w", cfg.Brokers, cfg.Topic, errors.Join(errs...))
+}
+
+func Publish(ctx context.Context, projectID string, message []byte)error{
+	return Writer.WriteMessages(ctx,
 	kafka.Message{
 		//Key: []byte(time.Now().String()),
 		Key: []byte(projectID),
 		Value: message,
 	},
 )
-}
\ No newline at end of file
+}
+
+// Close flushes any pending batches and releases the writer.
+func Close() error {
+	if Writer == nil {
+		return nil
+	}
+	return Writer.Close()
+}
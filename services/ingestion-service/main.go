@@ -1,21 +1,212 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/blobstore"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/config"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/db"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/enrich"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/grpcserver"
 	"github.com/k1ngalph0x/beacon/services/ingestion-service/handler"
 	"github.com/k1ngalph0x/beacon/services/ingestion-service/kafka"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/metrics"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/middleware"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/models"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/wal"
 )
 
 func main() {
 
-	kafka.InitKafka()
+	config, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	conn, err := db.ConnectDB()
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+
+	kafka.InitKafka(config.KAFKA)
+
+	buffer, err := wal.Open(config.WAL.Dir, config.WAL.MaxBytes, config.WAL.SegmentBytes)
+	if err != nil {
+		log.Fatalf("Error opening write-ahead log: %v", err)
+	}
+
+	metrics.NewGaugeFunc("beacon_wal_depth", "Events buffered on disk waiting for Kafka.", func() float64 {
+		return float64(buffer.Depth())
+	})
+	metrics.NewGaugeFunc("beacon_wal_bytes", "Bytes used by the write-ahead log.", func() float64 {
+		return float64(buffer.Size())
+	})
+	replayed := metrics.NewCounter("beacon_wal_replayed_total", "Buffered events published after Kafka recovered.")
+
+	err = conn.AutoMigrate(&models.Attachment{})
+	if err != nil {
+		log.Fatalf("Failed to migrate attachment table: %v", err)
+	}
+
+	blobs, err := blobstore.Open(config.BLOBS.Store, config.BLOBS.Dir)
+	if err != nil {
+		log.Fatalf("Error opening blob store: %v", err)
+	}
+
+	enricher, err := enrich.New(config.ENRICH.GeoIPDatabase)
+	if err != nil {
+		log.Fatalf("Error opening GeoIP database: %v", err)
+	}
+
+	handler := handler.NewHandler(config, buffer, conn, blobs, enricher)
+	projectAuth := middleware.NewProjectAuth(conn, config.AUTH.ProjectCacheTTL)
+	limiter := middleware.NewLimiter(config.SERVER.MaxInFlight)
+	inFlight := middleware.InFlightLimit(limiter)
+	eventLimit := middleware.BodyLimit(config.SERVER.MaxEventBytes)
 
 	router := gin.Default()
-	router.POST("/events", handler.Ingest)
-	router.Run(":8092")
 
-	fmt.Println("Running ingestion-service")
+	err = router.SetTrustedProxies(config.SERVER.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	router.GET("/metrics", metrics.Handler)
+	router.POST("/events", inFlight, eventLimit, projectAuth.RequireSecretKey(), handler.Ingest)
+	router.POST("/events/:event_id/attachments", inFlight, middleware.BodyLimit(config.BLOBS.MaxUploadBytes), projectAuth.RequireSecretKey(), handler.UploadAttachments)
+
+	browser := router.Group("/browser")
+	{
+		browser.OPTIONS("/events", projectAuth.Preflight())
+		browser.POST("/events", inFlight, eventLimit, projectAuth.RequirePublicKey(), middleware.BrowserCORS(), handler.IngestBrowser)
+	}
+
+	// OTLP/HTTP receiver. Exporters authenticate with the secret key in an
+	// "Authorization: Bearer" header.
+	otlpAPI := router.Group("/v1", inFlight, eventLimit, projectAuth.RequireSecretKey())
+	{
+		otlpAPI.POST("/logs", handler.OTLPLogs)
+		otlpAPI.POST("/traces", handler.OTLPTraces)
+	}
+
+	// Sentry SDK compatibility, for DSNs of the form
+	// https://<public key>@<ingestion host>/<project id>
+	sentryAPI := router.Group("/api/:project_id", inFlight, eventLimit)
+	{
+		sentryAPI.OPTIONS("/store/", projectAuth.Preflight())
+		sentryAPI.OPTIONS("/envelope/", projectAuth.Preflight())
+		sentryAPI.POST("/store/", projectAuth.RequireSentryKey(), middleware.BrowserCORS(), handler.SentryStore)
+		sentryAPI.POST("/envelope/", projectAuth.RequireSentryKey(), middleware.BrowserCORS(), handler.SentryEnvelope)
+	}
+
+	server := &http.Server{
+		Addr:    config.SERVER.Addr,
+		Handler: router,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	replayCtx, stopReplay := context.WithCancel(context.Background())
+	var replayDone sync.WaitGroup
+	replayDone.Add(1)
+	go func() {
+		defer replayDone.Done()
+		buffer.Replay(replayCtx, func(ctx context.Context, r wal.Record) error {
+			publishCtx, cancel := context.WithTimeout(ctx, config.SERVER.PublishTimeout)
+			defer cancel()
+
+			err := kafka.Publish(publishCtx, string(r.Key), r.Value)
+			if err == nil {
+				replayed.Inc()
+			}
+			return err
+		}, config.WAL.MaxReplayBackoff)
+	}()
+
+	filterCtx, stopFilters := context.WithCancel(context.Background())
+	var filtersDone sync.WaitGroup
+	filtersDone.Add(1)
+	go func() {
+		defer filtersDone.Done()
+		handler.Filters.Run(filterCtx, config.FILTER.FlushInterval)
+	}()
+
+	go func() {
+		log.Println("Running ingestion-service on", config.SERVER.Addr)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	grpcServer := grpcserver.Register(grpcserver.NewServer(handler, projectAuth), config.SERVER.MaxEventBytes, limiter)
+
+	listener, err := net.Listen("tcp", config.SERVER.GRPCAddr)
+	if err != nil {
+		log.Fatalf("Error listening on %s: %v", config.SERVER.GRPCAddr, err)
+	}
+
+	go func() {
+		log.Println("Running ingestion gRPC API on", config.SERVER.GRPCAddr)
+		err := grpcServer.Serve(listener)
+		if err != nil {
+			log.Fatalf("gRPC server error: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down ingestion-service")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.SERVER.ShutdownTimeout)
+	defer cancel()
+
+	// Shutdown waits for in-flight requests, so every accepted event has been
+	// handed to the writer before it is closed and flushed.
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Println("Failed to drain requests:", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		log.Println("Failed to drain gRPC streams:", shutdownCtx.Err())
+		grpcServer.Stop()
+	}
+
+	// Whatever the replayer has not delivered stays on disk for the next start.
+	stopReplay()
+	replayDone.Wait()
+
+	stopFilters()
+	filtersDone.Wait()
+
+	err = kafka.Close()
+	if err != nil {
+		log.Println("Failed to close kafka writer:", err)
+	}
+
+	err = buffer.Close()
+	if err != nil {
+		log.Println("Failed to close write-ahead log:", err)
+	}
 
-}
\ No newline at end of file
+	err = enricher.Close()
+	if err != nil {
+		log.Println("Failed to close GeoIP database:", err)
+	}
+}
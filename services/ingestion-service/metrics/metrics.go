@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A deliberately small registry that renders the Prometheus text format, so
+// ingestion can be scraped without pulling in a client library.
+
+type metric interface {
+	write(sb *strings.Builder)
+}
+
+var (
+	mu       sync.Mutex
+	registry []metric
+)
+
+func register(m metric) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry = append(registry, m)
+}
+
+type Counter struct {
+	name  string
+	help  string
+	value atomic.Int64
+}
+
+func NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	register(c)
+	return c
+}
+
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+func (c *Counter) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.value.Load())
+}
+
+// CounterVec is a family of counters distinguished by label values.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*atomic.Int64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*atomic.Int64)}
+	register(c)
+	return c
+}
+
+// Inc increments the counter for the given label values, which must be in
+// the order the labels were declared.
+func (c *CounterVec) Inc(values ...string) {
+	pairs := make([]string, len(c.labels))
+	for i, label := range c.labels {
+		pairs[i] = fmt.Sprintf("%s=%q", label, values[i])
+	}
+	key := strings.Join(pairs, ",")
+
+	c.mu.Lock()
+	v, ok := c.values[key]
+	if !ok {
+		v = &atomic.Int64{}
+		c.values[key] = v
+	}
+	c.mu.Unlock()
+
+	v.Add(1)
+}
+
+func (c *CounterVec) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, v := range c.values {
+		fmt.Fprintf(sb, "%s{%s} %d\n", c.name, key, v.Load())
+	}
+}
+
+type gaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn at scrape time.
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(&gaugeFunc{name: name, help: help, fn: fn})
+}
+
+func (g *gaugeFunc) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", g.name, g.help, g.name, g.name, g.fn())
+}
+
+func Handler(c *gin.Context) {
+	var sb strings.Builder
+
+	mu.Lock()
+	for _, m := range registry {
+		m.write(&sb)
+	}
+	mu.Unlock()
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4", []byte(sb.String()))
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/models"
+	"github.com/k1ngalph0x/beacon/services/ingestion-service/sentry"
+	"gorm.io/gorm"
+)
+
+const maxCachedKeys = 10000
+
+type cachedProject struct {
+	project   *models.Project
+	expiresAt time.Time
+}
+
+// ProjectAuth resolves API keys to projects. Lookups are cached for a short
+// TTL, including misses, so bad keys do not reach Postgres on every request.
+type ProjectAuth struct {
+	DB  *gorm.DB
+	TTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedProject
+}
+
+func NewProjectAuth(db *gorm.DB, ttl time.Duration) *ProjectAuth {
+	return &ProjectAuth{
+		DB:    db,
+		TTL:   ttl,
+		cache: make(map[string]cachedProject),
+	}
+}
+
+func (a *ProjectAuth) lookup(column, key string) (*models.Project, error) {
+	cacheKey := column + ":" + key
+
+	a.mu.Lock()
+	entry, ok := a.cache[cacheKey]
+	a.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.project, nil
+	}
+
+	var project models.Project
+	err := a.DB.Where(column+" = ?", key).First(&project).Error
+
+	var found *models.Project
+	if err == nil {
+		found = &project
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	a.mu.Lock()
+	if len(a.cache) >= maxCachedKeys {
+		for k, e := range a.cache {
+			if now.After(e.expiresAt) {
+				delete(a.cache, k)
+			}
+		}
+	}
+	a.cache[cacheKey] = cachedProject{project: found, expiresAt: now.Add(a.TTL)}
+	a.mu.Unlock()
+
+	return found, nil
+}
+
+// ByPublicKey returns the project for a public key, or nil if there is none.
+func (a *ProjectAuth) ByPublicKey(key string) (*models.Project, error) {
+	return a.lookup("public_key", key)
+}
+
+// BySecretKey returns the project for a secret key, or nil if there is none.
+func (a *ProjectAuth) BySecretKey(key string) (*models.Project, error) {
+	return a.lookup("secret_key", key)
+}
+
+func (a *ProjectAuth) authorize(c *gin.Context, project *models.Project, err error) bool {
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Abort()
+		return false
+	}
+
+	if project == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid project key"})
+		c.Abort()
+		return false
+	}
+
+	if !project.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Project is disabled"})
+		c.Abort()
+		return false
+	}
+
+	c.Set("project", project)
+	return true
+}
+
+// RequireSecretKey authenticates server-side SDKs that send
+// "Authorization: Bearer <secret key>".
+func (a *ProjectAuth) RequireSecretKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(strings.TrimSpace(c.GetHeader("Authorization")), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		project, err := a.BySecretKey(parts[1])
+		if !a.authorize(c, project, err) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePublicKey authenticates browser clients. The key is read from the
+// "key" query parameter, which is the only option for navigator.sendBeacon,
+// or from the X-Beacon-Key header.
+func (a *ProjectAuth) RequirePublicKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.Query("key")
+		if key == "" {
+			key = c.GetHeader("X-Beacon-Key")
+		}
+
+		if key == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		project, err := a.ByPublicKey(key)
+		if !a.authorize(c, project, err) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSentryKey authenticates Sentry SDKs by the public key in their DSN.
+// The project segment of the DSN path is not checked, as some SDKs insist on
+// it being numeric; the key alone identifies the project.
+func (a *ProjectAuth) RequireSentryKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := sentry.KeyFromRequest(c.Request)
+		if key == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		project, err := a.ByPublicKey(key)
+		if !a.authorize(c, project, err) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// CurrentProject returns the project set by one of the key middlewares.
+func CurrentProject(c *gin.Context) *models.Project {
+	project, _ := c.Get("project")
+	p, _ := project.(*models.Project)
+	return p
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OriginAllowed reports whether origin matches one of the project's allowed
+// origins. Entries may be "*", an exact origin such as
+// "https://app.example.com", or a subdomain wildcard such as "*.example.com"
+// or "https://*.example.com".
+func OriginAllowed(allowed []string, origin string) bool {
+	origin = strings.TrimRight(strings.ToLower(origin), "/")
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	for _, pattern := range allowed {
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+
+		scheme, host, hasScheme := strings.Cut(pattern, "://")
+		if !hasScheme {
+			host, scheme = scheme, ""
+		}
+
+		if scheme != "" && scheme != u.Scheme {
+			continue
+		}
+
+		if host == u.Host {
+			return true
+		}
+
+		if suffix, ok := strings.CutPrefix(host, "*."); ok && strings.HasSuffix(u.Host, "."+suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setCORSHeaders(c *gin.Context, origin string) {
+	c.Header("Access-Control-Allow-Origin", origin)
+	c.Header("Access-Control-Allow-Methods", "POST, OPTIONS")
+	c.Header("Access-Control-Allow-Headers", "Content-Type, X-Beacon-Key")
+	c.Header("Access-Control-Max-Age", "86400")
+	c.Header("Vary", "Origin")
+}
+
+// BrowserCORS enforces the project's allowed origins on browser requests. It
+// must run after RequirePublicKey. Requests without an Origin header are not
+// cross-origin and pass through.
+func BrowserCORS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		project := CurrentProject(c)
+		if project == nil || !OriginAllowed(project.AllowedOrigins, origin) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Origin not allowed"})
+			c.Abort()
+			return
+		}
+
+		setCORSHeaders(c, origin)
+		c.Next()
+	}
+}
+
+// Preflight answers CORS preflight requests for the browser and Sentry
+// endpoints. When the key is in the query string the origin is checked up
+// front; when it is sent as a header the preflight cannot see it, so the
+// origin is only enforced on the actual request.
+func (a *ProjectAuth) Preflight() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		key := c.Query("key")
+		if key == "" {
+			key = c.Query("sentry_key")
+		}
+
+		if key != "" {
+			project, err := a.ByPublicKey(key)
+			if err != nil || project == nil || !project.IsActive || !OriginAllowed(project.AllowedOrigins, origin) {
+				c.Status(http.StatusForbidden)
+				return
+			}
+		}
+
+		setCORSHeaders(c, origin)
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limiter is a fixed number of request slots. The HTTP and gRPC servers
+// share one, so neither can be used to get around the limit.
+type Limiter struct {
+	slots chan struct{}
+}
+
+func NewLimiter(max int) *Limiter {
+	return &Limiter{slots: make(chan struct{}, max)}
+}
+
+// Acquire takes a slot without waiting and reports whether one was free.
+// A successful Acquire must be paired with Release.
+func (l *Limiter) Acquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *Limiter) Release() {
+	<-l.slots
+}
+
+// InFlightLimit caps the number of requests being handled at once. When Kafka
+// slows down, publishes pile up; rejecting with 503 tells clients to back off
+// instead of letting goroutines and memory grow without bound.
+func InFlightLimit(limiter *Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !limiter.Acquire() {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many in-flight requests"})
+			c.Abort()
+			return
+		}
+
+		defer limiter.Release()
+		c.Next()
+	}
+}
+
+// BodyLimit rejects request bodies larger than max bytes. A declared
+// Content-Length over the limit is refused up front; otherwise reads past
+// the limit fail with *http.MaxBytesError, which handlers turn into a 413.
+func BodyLimit(max int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > max {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body exceeds the %d byte limit", max)})
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		c.Next()
+	}
+}
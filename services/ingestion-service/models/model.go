@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Project is a read-only view of the projects table owned by auth-service.
+type Project struct {
+	ID             string
+	UserId         string
+	PublicKey      string
+	SecretKey      string
+	IsActive       bool
+	AllowedOrigins pq.StringArray `gorm:"type:text[]"`
+	// Zero means the configured default quota applies.
+	AttachmentQuotaBytes int64
+}
+
+// InboundFilter is a view of the inbound_filters table owned by
+// auth-service. Ingestion only reads the rules and adds to DroppedCount.
+type InboundFilter struct {
+	ID        string
+	ProjectID string
+	Type      string
+	Pattern   string
+	Enabled   bool
+}
+
+// Attachment is a file uploaded alongside an event, such as a log file,
+// goroutine dump or minidump. The content lives in the blob store under
+// BlobKey; the row links it to the event and counts toward the project's
+// storage quota.
+type Attachment struct {
+	ID          string    `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID   string    `gorm:"type:uuid;not null;index" json:"project_id"`
+	EventID     string    `gorm:"type:uuid;not null;index" json:"event_id"`
+	Type        string    `gorm:"not null" json:"type"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `gorm:"not null" json:"size"`
+	BlobKey     string    `gorm:"not null" json:"-"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
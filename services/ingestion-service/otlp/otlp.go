@@ -0,0 +1,290 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Record is an error reported over OTLP, either an error-severity log record
+// or a span "exception" event, flattened out of the resource/scope nesting.
+type Record struct {
+	// Timestamp is when the record happened, or when it was received if the
+	// payload has no time.
+	Timestamp time.Time
+	// TimeUnixNano is the time given by the payload, 0 if none. Unlike
+	// Timestamp it is the same each time an exporter retries.
+	TimeUnixNano  uint64
+	Level         string
+	Message       string
+	ExceptionType string
+	StackTrace    string
+	TraceID       string
+	SpanID        string
+	Environment   string
+	Release       string
+	Tags          map[string]string
+}
+
+// maxTags bounds how many attributes are carried over as tags per record.
+const maxTags = 50
+
+func IsJSON(contentType string) bool {
+	return strings.HasPrefix(strings.ToLower(contentType), "application/json")
+}
+
+func DecodeLogs(body []byte, contentType string) (*collogspb.ExportLogsServiceRequest, error) {
+	req := &collogspb.ExportLogsServiceRequest{}
+	return req, decode(body, contentType, req)
+}
+
+func DecodeTraces(body []byte, contentType string) (*coltracepb.ExportTraceServiceRequest, error) {
+	req := &coltracepb.ExportTraceServiceRequest{}
+	return req, decode(body, contentType, req)
+}
+
+// Encode marshals an export response in the encoding the request used.
+func Encode(msg proto.Message, contentType string) ([]byte, error) {
+	if IsJSON(contentType) {
+		return protojson.Marshal(msg)
+	}
+	return proto.Marshal(msg)
+}
+
+func decode(body []byte, contentType string, msg proto.Message) error {
+	if !IsJSON(contentType) {
+		return proto.Unmarshal(body, msg)
+	}
+
+	// OTLP/JSON encodes trace and span IDs as hex, where protojson expects
+	// base64 for bytes fields.
+	var raw any
+	err := json.Unmarshal(body, &raw)
+	if err != nil {
+		return err
+	}
+
+	err = hexIDsToBase64(raw)
+	if err != nil {
+		return err
+	}
+
+	body, err = json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, msg)
+}
+
+func hexIDsToBase64(v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			switch key {
+			case "traceId", "spanId", "parentSpanId", "trace_id", "span_id", "parent_span_id":
+				s, ok := value.(string)
+				if !ok || s == "" {
+					continue
+				}
+				b, err := hex.DecodeString(s)
+				if err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+				v[key] = base64.StdEncoding.EncodeToString(b)
+			default:
+				err := hexIDsToBase64(value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		for _, item := range v {
+			err := hexIDsToBase64(item)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func stringValue(v *commonpb.AnyValue) string {
+	switch v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.GetStringValue()
+	case *commonpb.AnyValue_BoolValue:
+		return fmt.Sprint(v.GetBoolValue())
+	case *commonpb.AnyValue_IntValue:
+		return fmt.Sprint(v.GetIntValue())
+	case *commonpb.AnyValue_DoubleValue:
+		return fmt.Sprint(v.GetDoubleValue())
+	case *commonpb.AnyValue_BytesValue:
+		return hex.EncodeToString(v.GetBytesValue())
+	}
+	return ""
+}
+
+func attributes(kvs []*commonpb.KeyValue) map[string]string {
+	attrs := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		if s := stringValue(kv.GetValue()); s != "" {
+			attrs[kv.GetKey()] = s
+		}
+	}
+	return attrs
+}
+
+func unixNano(ns uint64) time.Time {
+	if ns == 0 {
+		return time.Now().UTC()
+	}
+	return time.Unix(0, int64(ns)).UTC()
+}
+
+func hexID(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// resourceRecord seeds a record with what the resource tells us about the
+// emitting service.
+func resourceRecord(resource map[string]string) Record {
+	r := Record{
+		Release: resource["service.version"],
+		Tags:    map[string]string{},
+	}
+
+	r.Environment = resource["deployment.environment.name"]
+	if r.Environment == "" {
+		r.Environment = resource["deployment.environment"]
+	}
+
+	if service := resource["service.name"]; service != "" {
+		r.Tags["service"] = service
+	}
+
+	return r
+}
+
+// applyAttributes copies the semantic-convention exception fields and, up to
+// maxTags, the remaining attributes as tags.
+func (r *Record) applyAttributes(attrs map[string]string) {
+	if t := attrs["exception.type"]; t != "" {
+		r.ExceptionType = t
+	}
+	if m := attrs["exception.message"]; m != "" {
+		r.Message = m
+	}
+	if s := attrs["exception.stacktrace"]; s != "" {
+		r.StackTrace = s
+	}
+
+	for k, v := range attrs {
+		if strings.HasPrefix(k, "exception.") || len(r.Tags) >= maxTags {
+			continue
+		}
+		r.Tags[k] = v
+	}
+}
+
+func isErrorLog(l *logspb.LogRecord) (string, bool) {
+	severity := l.GetSeverityNumber()
+	if severity >= logspb.SeverityNumber_SEVERITY_NUMBER_FATAL {
+		return "fatal", true
+	}
+	if severity >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR {
+		return "error", true
+	}
+	if severity != logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED {
+		return "", false
+	}
+
+	switch strings.ToUpper(l.GetSeverityText()) {
+	case "FATAL", "CRITICAL", "EMERGENCY", "ALERT":
+		return "fatal", true
+	case "ERROR", "ERR":
+		return "error", true
+	}
+	return "", false
+}
+
+// ErrorsFromLogs returns the log records at ERROR severity or above.
+func ErrorsFromLogs(req *collogspb.ExportLogsServiceRequest) []Record {
+	var records []Record
+
+	for _, rl := range req.GetResourceLogs() {
+		resource := attributes(rl.GetResource().GetAttributes())
+
+		for _, sl := range rl.GetScopeLogs() {
+			for _, l := range sl.GetLogRecords() {
+				level, ok := isErrorLog(l)
+				if !ok {
+					continue
+				}
+
+				r := resourceRecord(resource)
+				r.Level = level
+				r.Message = stringValue(l.GetBody())
+				r.TraceID = hexID(l.GetTraceId())
+				r.SpanID = hexID(l.GetSpanId())
+
+				r.TimeUnixNano = l.GetTimeUnixNano()
+				if r.TimeUnixNano == 0 {
+					r.TimeUnixNano = l.GetObservedTimeUnixNano()
+				}
+				r.Timestamp = unixNano(r.TimeUnixNano)
+
+				r.applyAttributes(attributes(l.GetAttributes()))
+				records = append(records, r)
+			}
+		}
+	}
+
+	return records
+}
+
+// ErrorsFromTraces returns the "exception" events recorded on spans.
+func ErrorsFromTraces(req *coltracepb.ExportTraceServiceRequest) []Record {
+	var records []Record
+
+	for _, rs := range req.GetResourceSpans() {
+		resource := attributes(rs.GetResource().GetAttributes())
+
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				for _, event := range span.GetEvents() {
+					if event.GetName() != "exception" {
+						continue
+					}
+
+					r := resourceRecord(resource)
+					r.Level = "error"
+					r.TraceID = hexID(span.GetTraceId())
+					r.SpanID = hexID(span.GetSpanId())
+					r.TimeUnixNano = event.GetTimeUnixNano()
+					r.Timestamp = unixNano(r.TimeUnixNano)
+					r.Tags["span"] = span.GetName()
+
+					r.applyAttributes(attributes(event.GetAttributes()))
+					records = append(records, r)
+				}
+			}
+		}
+	}
+
+	return records
+}
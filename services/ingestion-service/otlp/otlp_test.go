@@ -0,0 +1,209 @@
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func str(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func logsRequest(records ...*logspb.LogRecord) *collogspb.ExportLogsServiceRequest {
+	return &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				str("service.name", "checkout"),
+				str("service.version", "1.2.3"),
+				str("deployment.environment", "production"),
+			}},
+			ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}},
+		}},
+	}
+}
+
+func TestErrorsFromLogs(t *testing.T) {
+	const ts = uint64(1700000000000000000)
+
+	tests := []struct {
+		name      string
+		record    *logspb.LogRecord
+		wantLevel string
+		wantTime  uint64
+		skipped   bool
+	}{
+		{
+			name:      "error severity",
+			record:    &logspb.LogRecord{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, TimeUnixNano: ts},
+			wantLevel: "error",
+			wantTime:  ts,
+		},
+		{
+			name:      "fatal severity",
+			record:    &logspb.LogRecord{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_FATAL2, TimeUnixNano: ts},
+			wantLevel: "fatal",
+			wantTime:  ts,
+		},
+		{
+			name:    "warning severity is dropped",
+			record:  &logspb.LogRecord{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_WARN, SeverityText: "ERROR"},
+			skipped: true,
+		},
+		{
+			name:      "severity text when the number is unset",
+			record:    &logspb.LogRecord{SeverityText: "critical", TimeUnixNano: ts},
+			wantLevel: "fatal",
+			wantTime:  ts,
+		},
+		{
+			name:      "observed time when the time is unset",
+			record:    &logspb.LogRecord{SeverityText: "ERROR", ObservedTimeUnixNano: ts + 1},
+			wantLevel: "error",
+			wantTime:  ts + 1,
+		},
+		{
+			name:      "no time at all",
+			record:    &logspb.LogRecord{SeverityText: "ERROR"},
+			wantLevel: "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := ErrorsFromLogs(logsRequest(tt.record))
+			if tt.skipped {
+				if len(records) != 0 {
+					t.Fatalf("got %d records, want none", len(records))
+				}
+				return
+			}
+			if len(records) != 1 {
+				t.Fatalf("got %d records, want 1", len(records))
+			}
+
+			r := records[0]
+			if r.Level != tt.wantLevel {
+				t.Errorf("Level = %q, want %q", r.Level, tt.wantLevel)
+			}
+			if r.TimeUnixNano != tt.wantTime {
+				t.Errorf("TimeUnixNano = %d, want %d", r.TimeUnixNano, tt.wantTime)
+			}
+			if tt.wantTime != 0 && !r.Timestamp.Equal(time.Unix(0, int64(tt.wantTime))) {
+				t.Errorf("Timestamp = %v, want %v", r.Timestamp, time.Unix(0, int64(tt.wantTime)))
+			}
+			if r.Release != "1.2.3" || r.Environment != "production" || r.Tags["service"] != "checkout" {
+				t.Errorf("resource fields = %q, %q, %v", r.Release, r.Environment, r.Tags)
+			}
+		})
+	}
+}
+
+func TestErrorsFromLogsAttributes(t *testing.T) {
+	record := &logspb.LogRecord{
+		SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+		Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "request failed"}},
+		TraceId:        []byte{0xab, 0xcd},
+		Attributes: []*commonpb.KeyValue{
+			str("exception.type", "TimeoutError"),
+			str("exception.message", "upstream timed out"),
+			str("exception.stacktrace", "main.handler\n\tmain.go:10"),
+			str("http.route", "/pay"),
+		},
+	}
+
+	records := ErrorsFromLogs(logsRequest(record))
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+
+	r := records[0]
+	if r.ExceptionType != "TimeoutError" || r.Message != "upstream timed out" || r.StackTrace != "main.handler\n\tmain.go:10" {
+		t.Errorf("exception fields = %q, %q, %q", r.ExceptionType, r.Message, r.StackTrace)
+	}
+	if r.TraceID != "abcd" {
+		t.Errorf("TraceID = %q, want %q", r.TraceID, "abcd")
+	}
+	if r.Tags["http.route"] != "/pay" {
+		t.Errorf("Tags = %v, want http.route", r.Tags)
+	}
+	if _, ok := r.Tags["exception.type"]; ok {
+		t.Errorf("Tags = %v, exception attributes must not be tags", r.Tags)
+	}
+}
+
+func TestErrorsFromTraces(t *testing.T) {
+	req := &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Spans: []*tracepb.Span{{
+					Name:    "GET /pay",
+					TraceId: []byte{0x01},
+					SpanId:  []byte{0x02},
+					Events: []*tracepb.Span_Event{
+						{Name: "log", TimeUnixNano: 1},
+						{Name: "exception", TimeUnixNano: 2, Attributes: []*commonpb.KeyValue{str("exception.message", "boom")}},
+					},
+				}},
+			}},
+		}},
+	}
+
+	records := ErrorsFromTraces(req)
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+
+	r := records[0]
+	if r.Message != "boom" || r.TraceID != "01" || r.SpanID != "02" || r.TimeUnixNano != 2 || r.Tags["span"] != "GET /pay" {
+		t.Errorf("record = %+v", r)
+	}
+}
+
+func TestDecodeJSONHexIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantID  []byte
+		wantErr bool
+	}{
+		{
+			name:   "hex trace ID",
+			body:   `{"resourceLogs": [{"scopeLogs": [{"logRecords": [{"traceId": "abcd"}]}]}]}`,
+			wantID: []byte{0xab, 0xcd},
+		},
+		{
+			name:   "empty trace ID",
+			body:   `{"resourceLogs": [{"scopeLogs": [{"logRecords": [{"traceId": ""}]}]}]}`,
+			wantID: nil,
+		},
+		{
+			name:    "invalid hex",
+			body:    `{"resourceLogs": [{"scopeLogs": [{"logRecords": [{"traceId": "zz"}]}]}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := DecodeLogs([]byte(tt.body), "application/json")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeLogs error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := req.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0].GetTraceId()
+			if string(got) != string(tt.wantID) {
+				t.Errorf("TraceId = %x, want %x", got, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package sentry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The subset of the Sentry event schema Beacon understands. Several fields
+// have more than one wire form depending on SDK age, hence the custom
+// unmarshalers below.
+// https://develop.sentry.dev/sdk/data-model/event-payloads/
+
+type Event struct {
+	EventID     string     `json:"event_id"`
+	Timestamp   Timestamp  `json:"timestamp"`
+	Level       string     `json:"level"`
+	Platform    string     `json:"platform"`
+	Logger      string     `json:"logger"`
+	ServerName  string     `json:"server_name"`
+	Release     string     `json:"release"`
+	Environment string     `json:"environment"`
+	Message     Message    `json:"message"`
+	LogEntry    *Message   `json:"logentry"`
+	Exception   Exceptions `json:"exception"`
+	Tags        Tags       `json:"tags"`
+	User        *User      `json:"user"`
+	Fingerprint []string   `json:"fingerprint"`
+}
+
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Module     string      `json:"module"`
+	Stacktrace *Stacktrace `json:"stacktrace"`
+}
+
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+type Frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    *bool  `json:"in_app"`
+}
+
+type User struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+	IPAddress string `json:"ip_address"`
+}
+
+// Timestamp accepts either seconds since the epoch or an RFC 3339 string.
+type Timestamp time.Time
+
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(b, &seconds); err == nil {
+		whole, frac := math.Modf(seconds)
+		*t = Timestamp(time.Unix(int64(whole), int64(frac*1e9)).UTC())
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		// Older SDKs omit the zone and mean UTC.
+		parsed, err = time.Parse("2006-01-02T15:04:05.999999999", s)
+		if err != nil {
+			return err
+		}
+	}
+	*t = Timestamp(parsed)
+	return nil
+}
+
+// Message accepts a plain string or a {"message", "formatted"} object.
+type Message struct {
+	Formatted string `json:"formatted"`
+	Message   string `json:"message"`
+}
+
+func (m *Message) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		m.Formatted = s
+		return nil
+	}
+
+	type plain Message
+	return json.Unmarshal(b, (*plain)(m))
+}
+
+func (m Message) String() string {
+	if m.Formatted != "" {
+		return m.Formatted
+	}
+	return m.Message
+}
+
+// Exceptions accepts {"values": [...]} or a bare list.
+type Exceptions []Exception
+
+func (e *Exceptions) UnmarshalJSON(b []byte) error {
+	var wrapped struct {
+		Values []Exception `json:"values"`
+	}
+	if err := json.Unmarshal(b, &wrapped); err == nil {
+		*e = wrapped.Values
+		return nil
+	}
+
+	var list []Exception
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*e = list
+	return nil
+}
+
+// Tags accepts an object or a list of [key, value] pairs. SDKs also send
+// numbers and booleans as values; those are kept as strings, while null and
+// nested values are dropped.
+type Tags map[string]string
+
+func (t *Tags) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err == nil {
+		*t = make(map[string]string, len(m))
+		for k, raw := range m {
+			if v, ok := tagValue(raw); ok {
+				(*t)[k] = v
+			}
+		}
+		return nil
+	}
+
+	var pairs [][]json.RawMessage
+	if err := json.Unmarshal(b, &pairs); err != nil {
+		return err
+	}
+
+	*t = make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		if len(pair) != 2 {
+			continue
+		}
+		var k string
+		if err := json.Unmarshal(pair[0], &k); err != nil {
+			continue
+		}
+		if v, ok := tagValue(pair[1]); ok {
+			(*t)[k] = v
+		}
+	}
+	return nil
+}
+
+// tagValue converts a scalar JSON value to a tag value.
+func tagValue(raw json.RawMessage) (string, bool) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", false
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// authParam reads a parameter from the X-Sentry-Auth header, a "Sentry ..."
+// Authorization header or the query string, in that order.
+func authParam(r *http.Request, name string) string {
+	for _, header := range []string{r.Header.Get("X-Sentry-Auth"), r.Header.Get("Authorization")} {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(header), "Sentry ")
+		if !ok {
+			continue
+		}
+
+		for _, part := range strings.Split(rest, ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if k == name {
+				return v
+			}
+		}
+	}
+
+	return r.URL.Query().Get(name)
+}
+
+// KeyFromRequest extracts the DSN public key of a request.
+func KeyFromRequest(r *http.Request) string {
+	return authParam(r, "sentry_key")
+}
+
+// ClientFromRequest returns the SDK identifier, e.g. "sentry.go/0.27.0".
+func ClientFromRequest(r *http.Request) string {
+	return authParam(r, "sentry_client")
+}
+
+// ParseEnvelope returns the error events in a Sentry envelope. Other item
+// types (sessions, transactions, client reports, attachments) are skipped.
+// https://develop.sentry.dev/sdk/envelopes/
+func ParseEnvelope(body []byte) ([]*Event, error) {
+	r := bufio.NewReader(bytes.NewReader(body))
+
+	headerLine, err := readLine(r)
+	if err != nil {
+		return nil, fmt.Errorf("envelope header: %w", err)
+	}
+
+	var header struct {
+		EventID string `json:"event_id"`
+	}
+	err = json.Unmarshal(headerLine, &header)
+	if err != nil {
+		return nil, fmt.Errorf("envelope header: %w", err)
+	}
+
+	var events []*Event
+	for {
+		line, err := readLine(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var item struct {
+			Type   string `json:"type"`
+			Length *int   `json:"length"`
+		}
+		err = json.Unmarshal(line, &item)
+		if err != nil {
+			return nil, fmt.Errorf("item header: %w", err)
+		}
+
+		var payload []byte
+		if item.Length != nil {
+			if *item.Length < 0 || *item.Length > len(body) {
+				return nil, errors.New("item payload: invalid length")
+			}
+			payload = make([]byte, *item.Length)
+			_, err = io.ReadFull(r, payload)
+			if err != nil {
+				return nil, fmt.Errorf("item payload: %w", err)
+			}
+		} else {
+			payload, err = readLine(r)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("item payload: %w", err)
+			}
+		}
+
+		if item.Type != "event" || len(payload) == 0 {
+			continue
+		}
+
+		var event Event
+		err = json.Unmarshal(payload, &event)
+		if err != nil {
+			return nil, fmt.Errorf("event item: %w", err)
+		}
+
+		if event.EventID == "" {
+			event.EventID = header.EventID
+		}
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// readLine returns the next line without its newline. The last line of an
+// envelope may not be terminated.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadBytes('\n')
+	if errors.Is(err, io.EOF) && len(line) > 0 {
+		return line, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(line, []byte("\n")), nil
+}
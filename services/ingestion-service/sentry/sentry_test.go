@@ -0,0 +1,216 @@
+package sentry
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		check   func(t *testing.T, e Event)
+	}{
+		{
+			name:    "numeric timestamp",
+			payload: `{"timestamp": 1700000000.5}`,
+			check: func(t *testing.T, e Event) {
+				want := time.Unix(1700000000, 5e8).UTC()
+				if got := time.Time(e.Timestamp); !got.Equal(want) {
+					t.Errorf("Timestamp = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name:    "RFC 3339 timestamp",
+			payload: `{"timestamp": "2024-05-01T12:00:00Z"}`,
+			check: func(t *testing.T, e Event) {
+				want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+				if got := time.Time(e.Timestamp); !got.Equal(want) {
+					t.Errorf("Timestamp = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name:    "timestamp without zone",
+			payload: `{"timestamp": "2024-05-01T12:00:00.25"}`,
+			check: func(t *testing.T, e Event) {
+				want := time.Date(2024, 5, 1, 12, 0, 0, 25e7, time.UTC)
+				if got := time.Time(e.Timestamp); !got.Equal(want) {
+					t.Errorf("Timestamp = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name:    "string message",
+			payload: `{"message": "boom"}`,
+			check: func(t *testing.T, e Event) {
+				if got := e.Message.String(); got != "boom" {
+					t.Errorf("Message = %q, want %q", got, "boom")
+				}
+			},
+		},
+		{
+			name:    "object message prefers formatted",
+			payload: `{"message": {"message": "user %s", "formatted": "user 42"}}`,
+			check: func(t *testing.T, e Event) {
+				if got := e.Message.String(); got != "user 42" {
+					t.Errorf("Message = %q, want %q", got, "user 42")
+				}
+			},
+		},
+		{
+			name:    "wrapped exceptions",
+			payload: `{"exception": {"values": [{"type": "ValueError", "value": "bad"}]}}`,
+			check: func(t *testing.T, e Event) {
+				if len(e.Exception) != 1 || e.Exception[0].Type != "ValueError" {
+					t.Errorf("Exception = %+v", e.Exception)
+				}
+			},
+		},
+		{
+			name:    "bare exception list",
+			payload: `{"exception": [{"type": "KeyError"}]}`,
+			check: func(t *testing.T, e Event) {
+				if len(e.Exception) != 1 || e.Exception[0].Type != "KeyError" {
+					t.Errorf("Exception = %+v", e.Exception)
+				}
+			},
+		},
+		{
+			name:    "frame in_app flag",
+			payload: `{"exception": [{"stacktrace": {"frames": [{"function": "f", "in_app": false}, {"function": "g"}]}}]}`,
+			check: func(t *testing.T, e Event) {
+				frames := e.Exception[0].Stacktrace.Frames
+				if frames[0].InApp == nil || *frames[0].InApp {
+					t.Errorf("frame 0 InApp = %v, want false", frames[0].InApp)
+				}
+				if frames[1].InApp != nil {
+					t.Errorf("frame 1 InApp = %v, want unset", *frames[1].InApp)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e Event
+			err := json.Unmarshal([]byte(tt.payload), &e)
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			tt.check(t, e)
+		})
+	}
+}
+
+func TestTagsUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    Tags
+	}{
+		{
+			name:    "object",
+			payload: `{"a": "1", "b": "x"}`,
+			want:    Tags{"a": "1", "b": "x"},
+		},
+		{
+			name:    "pairs",
+			payload: `[["a", "1"], ["b", "x"]]`,
+			want:    Tags{"a": "1", "b": "x"},
+		},
+		{
+			name:    "numbers and booleans become strings",
+			payload: `{"n": 42, "f": 1.5, "ok": true}`,
+			want:    Tags{"n": "42", "f": "1.5", "ok": "true"},
+		},
+		{
+			name:    "null and nested values are dropped",
+			payload: `{"a": null, "b": {"c": 1}, "d": [1], "e": "kept"}`,
+			want:    Tags{"e": "kept"},
+		},
+		{
+			name:    "malformed pairs are skipped",
+			payload: `[["a"], [1, "x"], ["b", 2], ["c", null]]`,
+			want:    Tags{"b": "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Tags
+			err := json.Unmarshal([]byte(tt.payload), &got)
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnvelope(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantIDs []string
+		wantErr bool
+	}{
+		{
+			name: "event item without length",
+			body: `{"event_id": "aaa"}
+{"type": "event"}
+{"message": "boom"}
+`,
+			wantIDs: []string{"aaa"},
+		},
+		{
+			name:    "event item with length and its own ID",
+			body:    "{\"event_id\": \"aaa\"}\n{\"type\": \"event\", \"length\": 20}\n{\"event_id\": \"bbb\"}\n",
+			wantIDs: []string{"bbb"},
+		},
+		{
+			name: "other items are skipped",
+			body: `{"event_id": "aaa"}
+{"type": "session"}
+{"status": "ok"}
+{"type": "event"}
+{"message": "boom"}`,
+			wantIDs: []string{"aaa"},
+		},
+		{
+			name:    "length past the body",
+			body:    "{}\n{\"type\": \"event\", \"length\": 1000}\n{}",
+			wantErr: true,
+		},
+		{
+			name:    "malformed header",
+			body:    "not json\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := ParseEnvelope([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEnvelope error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var ids []string
+			for _, e := range events {
+				ids = append(ids, e.EventID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("event IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,489 @@
+package wal
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrFull = errors.New("wal: buffer is full")
+
+// Each record is stored as a 4 byte payload length, a 4 byte CRC32 of the
+// payload, then the payload itself: a 2 byte key length, the key and the value.
+const headerSize = 8
+
+const cursorFile = "cursor"
+
+type Record struct {
+	Key   []byte
+	Value []byte
+}
+
+// WAL is a bounded, segmented on-disk queue. Events are appended while Kafka
+// is unavailable and read back in order by Replay once it recovers. The read
+// position is persisted so buffered events survive a restart.
+type WAL struct {
+	mu           sync.Mutex
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+
+	segments []uint64
+	size     int64
+	depth    int64
+
+	writer     *os.File
+	writerID   uint64
+	writerSize int64
+
+	reader     *os.File
+	readerID   uint64
+	readOffset int64
+	pending    int64
+
+	notify chan struct{}
+}
+
+func segmentName(id uint64) string {
+	return fmt.Sprintf("%020d.wal", id)
+}
+
+func Open(dir string, maxBytes, segmentBytes int64) (*WAL, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WAL{
+		dir:          dir,
+		maxBytes:     maxBytes,
+		segmentBytes: segmentBytes,
+		notify:       make(chan struct{}, 1),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".wal")
+		if name == entry.Name() {
+			continue
+		}
+		id, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		w.segments = append(w.segments, id)
+	}
+	sort.Slice(w.segments, func(i, j int) bool { return w.segments[i] < w.segments[j] })
+
+	err = w.loadCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(w.segments) == 0 {
+		if w.readerID == 0 {
+			w.readerID = 1
+		}
+		w.segments = []uint64{w.readerID}
+		w.readOffset = 0
+	}
+
+	w.writerID = w.segments[len(w.segments)-1]
+	w.writer, err = os.OpenFile(w.path(w.writerID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := w.writer.Stat()
+	if err != nil {
+		return nil, err
+	}
+	w.writerSize = info.Size()
+
+	return w, nil
+}
+
+func (w *WAL) path(id uint64) string {
+	return filepath.Join(w.dir, segmentName(id))
+}
+
+func (w *WAL) loadCursor() error {
+	data, err := os.ReadFile(filepath.Join(w.dir, cursorFile))
+	if errors.Is(err, os.ErrNotExist) {
+		if len(w.segments) > 0 {
+			w.readerID = w.segments[0]
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Sscanf(string(data), "%d %d", &w.readerID, &w.readOffset)
+	if err != nil {
+		return fmt.Errorf("wal: corrupt cursor: %w", err)
+	}
+
+	// Segments before the cursor were fully replayed but not yet removed.
+	for len(w.segments) > 0 && w.segments[0] < w.readerID {
+		os.Remove(w.path(w.segments[0]))
+		w.segments = w.segments[1:]
+	}
+
+	if len(w.segments) > 0 && w.segments[0] > w.readerID {
+		w.readerID = w.segments[0]
+		w.readOffset = 0
+	}
+
+	return nil
+}
+
+// scan counts the unreplayed records and truncates a segment at the first
+// torn or corrupt record, which is what a crash mid-append leaves behind.
+func (w *WAL) scan() error {
+	for _, id := range w.segments {
+		f, err := os.OpenFile(w.path(id), os.O_RDWR, 0o644)
+		if err != nil {
+			return err
+		}
+
+		var offset int64
+		if id == w.readerID {
+			offset = w.readOffset
+		}
+
+		for {
+			_, n, err := readRecord(f, offset)
+			if err != nil {
+				break
+			}
+			offset += n
+			w.depth++
+		}
+
+		err = f.Truncate(offset)
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		info, err := os.Stat(w.path(id))
+		if err != nil {
+			return err
+		}
+		w.size += info.Size()
+	}
+
+	return nil
+}
+
+func readRecord(f *os.File, offset int64) (Record, int64, error) {
+	header := make([]byte, headerSize)
+	_, err := f.ReadAt(header, offset)
+	if err != nil {
+		return Record{}, 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+
+	// A corrupt length must not size the allocation: anything running past
+	// the end of the segment is a truncated tail, like a short read.
+	info, err := f.Stat()
+	if err != nil {
+		return Record{}, 0, err
+	}
+	if int64(length) > info.Size()-offset-headerSize {
+		return Record{}, 0, io.EOF
+	}
+
+	payload := make([]byte, length)
+	_, err = f.ReadAt(payload, offset+headerSize)
+	if err != nil {
+		return Record{}, 0, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != sum || len(payload) < 2 {
+		return Record{}, 0, errors.New("wal: corrupt record")
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(payload[0:2]))
+	if 2+keyLen > len(payload) {
+		return Record{}, 0, errors.New("wal: corrupt record")
+	}
+
+	record := Record{
+		Key:   payload[2 : 2+keyLen],
+		Value: payload[2+keyLen:],
+	}
+
+	return record, headerSize + int64(length), nil
+}
+
+func encodeRecord(r Record) []byte {
+	payload := make([]byte, 2+len(r.Key)+len(r.Value))
+	binary.BigEndian.PutUint16(payload[0:2], uint16(len(r.Key)))
+	copy(payload[2:], r.Key)
+	copy(payload[2+len(r.Key):], r.Value)
+
+	buf := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[headerSize:], payload)
+
+	return buf
+}
+
+// Append durably buffers a record. It returns ErrFull once the buffer has
+// reached its size limit.
+func (w *WAL) Append(r Record) error {
+	buf := encodeRecord(r)
+	n := int64(len(buf))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.size+n > w.maxBytes {
+		return ErrFull
+	}
+
+	if w.writerSize > 0 && w.writerSize+n > w.segmentBytes {
+		err := w.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := w.writer.Write(buf)
+	if err != nil {
+		return err
+	}
+
+	err = w.writer.Sync()
+	if err != nil {
+		return err
+	}
+
+	w.size += n
+	w.writerSize += n
+	w.depth++
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (w *WAL) rotate() error {
+	err := w.writer.Close()
+	if err != nil {
+		return err
+	}
+
+	w.writerID++
+	w.writer, err = os.OpenFile(w.path(w.writerID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w.writerSize = 0
+	w.segments = append(w.segments, w.writerID)
+	return nil
+}
+
+// Depth is the number of records waiting to be replayed.
+func (w *WAL) Depth() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.depth
+}
+
+// Size is the number of bytes the buffer occupies on disk.
+func (w *WAL) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+// next returns the oldest unreplayed record without consuming it.
+func (w *WAL) next() (Record, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.depth == 0 {
+		return Record{}, io.EOF
+	}
+
+	for {
+		if w.reader == nil {
+			f, err := os.Open(w.path(w.readerID))
+			if err != nil {
+				return Record{}, err
+			}
+			w.reader = f
+		}
+
+		record, n, err := readRecord(w.reader, w.readOffset)
+		if err == nil {
+			w.pending = n
+			return record, nil
+		}
+
+		if !errors.Is(err, io.EOF) || w.readerID >= w.writerID {
+			return Record{}, err
+		}
+
+		err = w.dropSegment()
+		if err != nil {
+			return Record{}, err
+		}
+	}
+}
+
+// dropSegment removes the fully replayed reader segment and moves on to the
+// next one.
+func (w *WAL) dropSegment() error {
+	w.reader.Close()
+	w.reader = nil
+
+	info, err := os.Stat(w.path(w.readerID))
+	if err == nil {
+		w.size -= info.Size()
+	}
+
+	err = os.Remove(w.path(w.readerID))
+	if err != nil {
+		return err
+	}
+
+	w.segments = w.segments[1:]
+	w.readerID = w.segments[0]
+	w.readOffset = 0
+
+	return w.saveCursor()
+}
+
+// ack consumes the record returned by the last call to next.
+func (w *WAL) ack() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.readOffset += w.pending
+	w.pending = 0
+	w.depth--
+
+	if w.depth > 0 {
+		return w.saveCursor()
+	}
+
+	// Fully drained: start over on a fresh segment to reclaim the disk space.
+	if w.reader != nil {
+		w.reader.Close()
+		w.reader = nil
+	}
+
+	err := w.rotate()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range w.segments[:len(w.segments)-1] {
+		os.Remove(w.path(id))
+	}
+
+	w.segments = []uint64{w.writerID}
+	w.readerID = w.writerID
+	w.readOffset = 0
+	w.size = 0
+
+	return w.saveCursor()
+}
+
+func (w *WAL) saveCursor() error {
+	tmp := filepath.Join(w.dir, cursorFile+".tmp")
+	data := fmt.Sprintf("%d %d", w.readerID, w.readOffset)
+
+	err := os.WriteFile(tmp, []byte(data), 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(w.dir, cursorFile))
+}
+
+// Replay publishes buffered records in order until ctx is cancelled. A record
+// is only consumed once publish succeeds, so an outage stalls the replay with
+// an increasing backoff rather than skipping records.
+func (w *WAL) Replay(ctx context.Context, publish func(context.Context, Record) error, maxBackoff time.Duration) {
+	backoff := 500 * time.Millisecond
+
+	for ctx.Err() == nil {
+		record, err := w.next()
+		if errors.Is(err, io.EOF) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.notify:
+			}
+			continue
+		}
+
+		if err == nil {
+			err = publish(ctx, record)
+		}
+
+		if err != nil {
+			log.Printf("WAL replay failed, retrying in %s: %v", backoff, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			continue
+		}
+
+		backoff = 500 * time.Millisecond
+
+		err = w.ack()
+		if err != nil {
+			log.Println("WAL failed to advance cursor:", err)
+		}
+	}
+}
+
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.reader != nil {
+		w.reader.Close()
+	}
+	return w.writer.Close()
+}
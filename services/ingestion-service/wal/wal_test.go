@@ -0,0 +1,192 @@
+package wal
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func records(n int) []Record {
+	out := make([]Record, n)
+	for i := range out {
+		out[i] = Record{Key: []byte("project"), Value: []byte{byte('a' + i)}}
+	}
+	return out
+}
+
+// drain consumes every buffered record in order.
+func drain(t *testing.T, w *WAL) []Record {
+	t.Helper()
+
+	var out []Record
+	for {
+		r, err := w.next()
+		if errors.Is(err, io.EOF) {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		out = append(out, Record{Key: append([]byte{}, r.Key...), Value: append([]byte{}, r.Value...)})
+
+		err = w.ack()
+		if err != nil {
+			t.Fatalf("ack: %v", err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name         string
+		records      int
+		segmentBytes int64
+		// Records replayed before the WAL is reopened.
+		replayFirst int
+	}{
+		{name: "single segment", records: 3, segmentBytes: 1 << 20},
+		{name: "across segments", records: 5, segmentBytes: 20},
+		{name: "resumes from the cursor", records: 5, segmentBytes: 1 << 20, replayFirst: 2},
+		{name: "resumes across segments", records: 5, segmentBytes: 20, replayFirst: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			want := records(tt.records)
+
+			w, err := Open(dir, 1<<20, tt.segmentBytes)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			for _, r := range want {
+				err = w.Append(r)
+				if err != nil {
+					t.Fatalf("Append: %v", err)
+				}
+			}
+			for i := 0; i < tt.replayFirst; i++ {
+				_, err = w.next()
+				if err != nil {
+					t.Fatalf("next: %v", err)
+				}
+				err = w.ack()
+				if err != nil {
+					t.Fatalf("ack: %v", err)
+				}
+			}
+			w.Close()
+
+			w, err = Open(dir, 1<<20, tt.segmentBytes)
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			defer w.Close()
+
+			want = want[tt.replayFirst:]
+			if got := w.Depth(); got != int64(len(want)) {
+				t.Fatalf("Depth = %d, want %d", got, len(want))
+			}
+
+			got := drain(t, w)
+			if len(got) != len(want) {
+				t.Fatalf("replayed %d records, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if string(got[i].Key) != string(want[i].Key) || string(got[i].Value) != string(want[i].Value) {
+					t.Errorf("record %d = %q/%q, want %q/%q", i, got[i].Key, got[i].Value, want[i].Key, want[i].Value)
+				}
+			}
+			if w.Depth() != 0 {
+				t.Errorf("Depth after drain = %d, want 0", w.Depth())
+			}
+		})
+	}
+}
+
+func TestCorruptTail(t *testing.T) {
+	hugeLength := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(hugeLength[0:4], 0xFFFFFFF0)
+
+	badChecksum := encodeRecord(Record{Key: []byte("project"), Value: []byte("z")})
+	badChecksum[len(badChecksum)-1] ^= 0xFF
+
+	tests := []struct {
+		name string
+		tail []byte
+	}{
+		{name: "torn header", tail: []byte{0, 0, 0}},
+		{name: "torn payload", tail: encodeRecord(Record{Key: []byte("project"), Value: []byte("zz")})[:headerSize+3]},
+		{name: "length past the segment end", tail: hugeLength},
+		{name: "checksum mismatch", tail: badChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			want := records(2)
+
+			w, err := Open(dir, 1<<20, 1<<20)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			for _, r := range want {
+				err = w.Append(r)
+				if err != nil {
+					t.Fatalf("Append: %v", err)
+				}
+			}
+			intact := w.Size()
+			w.Close()
+
+			segment := filepath.Join(dir, segmentName(1))
+			f, err := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				t.Fatalf("open segment: %v", err)
+			}
+			_, err = f.Write(tt.tail)
+			f.Close()
+			if err != nil {
+				t.Fatalf("write tail: %v", err)
+			}
+
+			w, err = Open(dir, 1<<20, 1<<20)
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			defer w.Close()
+
+			if got := w.Depth(); got != int64(len(want)) {
+				t.Errorf("Depth = %d, want %d", got, len(want))
+			}
+			if got := w.Size(); got != intact {
+				t.Errorf("Size = %d, want the tail truncated to %d", got, intact)
+			}
+			if got := drain(t, w); len(got) != len(want) {
+				t.Errorf("replayed %d records, want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestAppendFull(t *testing.T) {
+	w, err := Open(t.TempDir(), 40, 1<<20)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer w.Close()
+
+	r := Record{Key: []byte("project"), Value: []byte("0123456789")}
+	err = w.Append(r)
+	if err != nil {
+		t.Fatalf("first Append: %v", err)
+	}
+
+	err = w.Append(r)
+	if !errors.Is(err, ErrFull) {
+		t.Fatalf("second Append = %v, want ErrFull", err)
+	}
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,6 +13,45 @@ import (
 type Config struct {
 	DB PostgresConfig
 	TOKEN TokenConfig
+	DEDUP DedupConfig
+	GROUPING GroupingConfig
+	BATCH BatchConfig
+	RETRY RetryConfig
+	ADMIN AdminConfig
+	KAFKA KafkaConfig
+}
+
+type KafkaConfig struct {
+	Brokers []string
+}
+
+type RetryConfig struct {
+	// Attempts at processing an event before it is dead-lettered.
+	Attempts int
+	// Wait before the first retry; it doubles with every attempt.
+	Backoff time.Duration
+}
+
+type AdminConfig struct {
+	// Users allowed to call the /admin endpoints.
+	UserIDs []string
+}
+
+type BatchConfig struct {
+	// Most events processed in one batch.
+	Size int
+	// How long a batch waits for more events after its first.
+	Window time.Duration
+}
+
+type GroupingConfig struct {
+	// How long events are also matched against the previous grouping config
+	// after a project switches.
+	Transition time.Duration
+}
+
+type DedupConfig struct {
+	Retention time.Duration
 }
 
 type TokenConfig struct{
@@ -49,5 +91,70 @@ func LoadConfig() (*Config, error) {
 		},
 	}
 
+	config.DEDUP.Retention = 24 * time.Hour
+	if v := os.Getenv("EVENT_DEDUP_RETENTION"); v != "" {
+		retention, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		config.DEDUP.Retention = retention
+	}
+
+	config.GROUPING.Transition = 7 * 24 * time.Hour
+	if v := os.Getenv("GROUPING_TRANSITION"); v != "" {
+		transition, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		config.GROUPING.Transition = transition
+	}
+
+	config.BATCH.Size = 500
+	if v := os.Getenv("EVENT_BATCH_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		config.BATCH.Size = size
+	}
+
+	config.BATCH.Window = time.Second
+	if v := os.Getenv("EVENT_BATCH_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		config.BATCH.Window = window
+	}
+
+	config.RETRY.Attempts = 3
+	if v := os.Getenv("EVENT_MAX_ATTEMPTS"); v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		config.RETRY.Attempts = attempts
+	}
+
+	config.RETRY.Backoff = time.Second
+	if v := os.Getenv("EVENT_RETRY_BACKOFF"); v != "" {
+		backoff, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		config.RETRY.Backoff = backoff
+	}
+
+	config.KAFKA.Brokers = []string{"localhost:9092"}
+	if v := os.Getenv("KAFKA_BROKERS"); v != "" {
+		config.KAFKA.Brokers = strings.Split(v, ",")
+	}
+
+	for _, id := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			config.ADMIN.UserIDs = append(config.ADMIN.UserIDs, id)
+		}
+	}
+
 	return config, nil
 }
\ No newline at end of file
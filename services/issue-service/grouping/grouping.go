@@ -0,0 +1,211 @@
+package grouping
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+)
+
+// Grouping configurations. The version suffix is bumped whenever a
+// strategy's output changes, so issues record exactly which algorithm
+// produced their fingerprint.
+const (
+	// Legacy hashes the raw message and full stack trace.
+	Legacy = "legacy:v1"
+	// Stack hashes the function names of in-app frames.
+	Stack = "stack:v1"
+	// Message hashes the message with variable parts replaced.
+	Message = "message:v1"
+	// Exception hashes the exception type and the top in-app frame.
+	Exception = "exception:v1"
+
+	Default = Legacy
+)
+
+// Input is the part of an event grouping looks at.
+type Input struct {
+	Message    string
+	StackTrace *string
+	// Fingerprint set by the client. When present it replaces the
+	// strategy, except where an entry is the DefaultPlaceholder.
+	Fingerprint []string
+	// Rules configured for the project. A matching fingerprint rule takes
+	// precedence over the client fingerprint.
+	Rules Rules
+}
+
+// DefaultPlaceholder in a client fingerprint stands for the components the
+// configured strategy would produce.
+const DefaultPlaceholder = "{{ default }}"
+
+func isDefaultPlaceholder(part string) bool {
+	return strings.Join(strings.Fields(part), "") == "{{default}}"
+}
+
+func (in Input) stack() string {
+	if in.StackTrace == nil {
+		return ""
+	}
+	return *in.StackTrace
+}
+
+// Frames parses the stack trace and applies the project's stack rules.
+func (in Input) Frames() []Frame {
+	frames := ParseStack(in.stack())
+	applyStackRules(frames, in.Rules.Stack)
+	return frames
+}
+
+// Result explains how an event was grouped.
+type Result struct {
+	Config      string   `json:"grouping_config"`
+	Fingerprint string   `json:"fingerprint"`
+	Components  []string `json:"components"`
+	// ID of the fingerprint rule that matched, if any.
+	Rule   string  `json:"rule_id,omitempty"`
+	Frames []Frame `json:"frames"`
+}
+
+var strategies = map[string]func(Input, []Frame) []string{
+	Legacy:    legacy,
+	Stack:     stackFrames,
+	Message:   messageTemplate,
+	Exception: exceptionFrame,
+}
+
+// Configs lists the available grouping configurations.
+func Configs() []string {
+	return []string{Legacy, Stack, Message, Exception}
+}
+
+func Valid(config string) bool {
+	_, ok := strategies[config]
+	return ok
+}
+
+// Fingerprint hashes an event under a grouping configuration. Unknown
+// configurations fall back to Default.
+func Fingerprint(config string, in Input) string {
+	return Explain(config, in).Fingerprint
+}
+
+// Explain groups an event like Fingerprint and reports the components that
+// were hashed.
+func Explain(config string, in Input) Result {
+	strategy, ok := strategies[config]
+	if !ok {
+		config = Default
+		strategy = strategies[Default]
+	}
+
+	result := Result{Config: config, Frames: in.Frames()}
+
+	fingerprint := in.Fingerprint
+	for _, rule := range in.Rules.Fingerprint {
+		if rule.matches(in, result.Frames) {
+			fingerprint = rule.Fingerprint
+			result.Rule = rule.ID
+			break
+		}
+	}
+
+	custom := false
+	for _, part := range fingerprint {
+		custom = custom || !isDefaultPlaceholder(part)
+	}
+
+	// ["{{ default }}"] is the same as no fingerprint.
+	if !custom {
+		result.Components = strategy(in, result.Frames)
+		result.Fingerprint = hash(result.Components)
+		return result
+	}
+
+	result.Components = []string{"custom"}
+	for _, part := range fingerprint {
+		if isDefaultPlaceholder(part) {
+			result.Components = append(result.Components, strategy(in, result.Frames)...)
+			continue
+		}
+		result.Components = append(result.Components, part)
+	}
+	result.Fingerprint = hash(result.Components)
+	return result
+}
+
+func hash(components []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(components, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+func legacy(in Input, _ []Frame) []string {
+	if in.StackTrace == nil {
+		return []string{in.Message}
+	}
+	return []string{in.Message, *in.StackTrace}
+}
+
+func messageTemplate(in Input, _ []Frame) []string {
+	return []string{"message", Template(in.Message)}
+}
+
+// stackFrames groups by the in-app call path. Line numbers are left out so
+// unrelated edits do not split issues. Without in-app frames all frames are
+// used, and without a stack trace the message template.
+func stackFrames(in Input, frames []Frame) []string {
+	if len(frames) == 0 {
+		return messageTemplate(in, frames)
+	}
+
+	components := []string{"stack"}
+	for _, f := range frames {
+		if f.InApp {
+			components = append(components, frameKey(f))
+		}
+	}
+
+	if len(components) == 1 {
+		for _, f := range frames {
+			components = append(components, frameKey(f))
+		}
+	}
+
+	return components
+}
+
+func exceptionFrame(in Input, frames []Frame) []string {
+	typ := ExceptionType(in.Message)
+	if typ == "" {
+		typ = Template(in.Message)
+	}
+
+	components := []string{"exception", typ}
+	if top, ok := topFrame(frames); ok {
+		components = append(components, frameKey(top))
+	}
+	return components
+}
+
+// topFrame returns the innermost in-app frame, or the innermost frame when
+// none is in-app.
+func topFrame(frames []Frame) (Frame, bool) {
+	for _, f := range frames {
+		if f.InApp {
+			return f, true
+		}
+	}
+	if len(frames) > 0 {
+		return frames[0], true
+	}
+	return Frame{}, false
+}
+
+// frameKey identifies a frame by function, or by file name for anonymous
+// frames. Directories are dropped as they differ between deployments.
+func frameKey(f Frame) string {
+	if f.Function != "" {
+		return f.Function
+	}
+	return path.Base(f.File)
+}
@@ -0,0 +1,142 @@
+package grouping
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Rule matchers. "message" and "type" look at the event; "function",
+// "module" and "file" match when any frame of the stack trace does.
+const (
+	MatchMessage  = "message"
+	MatchType     = "type"
+	MatchFunction = "function"
+	MatchModule   = "module"
+	MatchFile     = "file"
+)
+
+// FingerprintRule gives events matching Pattern the fingerprint Fingerprint,
+// which may contain DefaultPlaceholder. Create it with NewFingerprintRule.
+type FingerprintRule struct {
+	ID          string
+	Matcher     string
+	Pattern     string
+	Fingerprint []string
+
+	glob Glob
+}
+
+func NewFingerprintRule(id, matcher, pattern string, fingerprint []string) FingerprintRule {
+	return FingerprintRule{ID: id, Matcher: matcher, Pattern: pattern, Fingerprint: fingerprint, glob: CompileGlob(pattern)}
+}
+
+// StackRule marks frames matching Pattern as in-app or not, overriding the
+// built-in guess. Create it with NewStackRule.
+type StackRule struct {
+	ID      string
+	Matcher string
+	Pattern string
+	InApp   bool
+
+	glob Glob
+}
+
+func NewStackRule(id, matcher, pattern string, inApp bool) StackRule {
+	return StackRule{ID: id, Matcher: matcher, Pattern: pattern, InApp: inApp, glob: CompileGlob(pattern)}
+}
+
+// Rules are a project's grouping rules, in the order they apply.
+type Rules struct {
+	Fingerprint []FingerprintRule
+	Stack       []StackRule
+}
+
+// ValidateRule checks a matcher and pattern. Stack rules only match frames.
+func ValidateRule(matcher, pattern string, stack bool) error {
+	switch matcher {
+	case MatchFunction, MatchModule, MatchFile:
+	case MatchMessage, MatchType:
+		if stack {
+			return errors.New("stack rules match function, module or file")
+		}
+	default:
+		return errors.New("matcher must be one of message, type, function, module, file")
+	}
+
+	if strings.TrimSpace(pattern) == "" {
+		return errors.New("pattern is required")
+	}
+	return nil
+}
+
+// Glob is a compiled pattern where * matches any run of characters,
+// including "/", and ? a single character. Matching is case-insensitive.
+// Rules compile their patterns once, as they are matched against every
+// frame of every event.
+type Glob struct {
+	re *regexp.Regexp
+}
+
+func CompileGlob(pattern string) Glob {
+	var re strings.Builder
+	re.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+
+	// Every rune outside * and ? is quoted, so the expression always compiles.
+	return Glob{re: regexp.MustCompile(re.String())}
+}
+
+func (g Glob) Match(s string) bool {
+	return g.re != nil && g.re.MatchString(s)
+}
+
+func frameMatches(matcher string, glob Glob, f Frame) bool {
+	switch matcher {
+	case MatchFunction:
+		return glob.Match(f.Function)
+	case MatchModule:
+		return glob.Match(f.Module)
+	case MatchFile:
+		return glob.Match(f.File)
+	}
+	return false
+}
+
+// applyStackRules lets the last matching rule decide whether each frame is
+// in-app.
+func applyStackRules(frames []Frame, rules []StackRule) {
+	for i := range frames {
+		for _, rule := range rules {
+			if frameMatches(rule.Matcher, rule.glob, frames[i]) {
+				frames[i].InApp = rule.InApp
+			}
+		}
+	}
+}
+
+func (r FingerprintRule) matches(in Input, frames []Frame) bool {
+	switch r.Matcher {
+	case MatchMessage:
+		return r.glob.Match(in.Message)
+	case MatchType:
+		return r.glob.Match(ExceptionType(in.Message))
+	}
+
+	for _, f := range frames {
+		if frameMatches(r.Matcher, r.glob, f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package grouping
+
+import (
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{pattern: "db.*", s: "db.Query", want: true},
+		{pattern: "db.*", s: "dbxQuery", want: false},
+		{pattern: "*timeout*", s: "read: connection TIMEOUT after 5s", want: true},
+		{pattern: "github.com/acme/*", s: "github.com/acme/shared/db", want: true},
+		{pattern: "file?.go", s: "file1.go", want: true},
+		{pattern: "file?.go", s: "file12.go", want: false},
+		{pattern: "a+b", s: "a+b", want: true},
+		{pattern: "a+b", s: "aab", want: false},
+		{pattern: "line*", s: "line one\nline two", want: true},
+		{pattern: "", s: "", want: true},
+		{pattern: "", s: "x", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := CompileGlob(tt.pattern).Match(tt.s); got != tt.want {
+			t.Errorf("CompileGlob(%q).Match(%q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+
+	if (Glob{}).Match("") {
+		t.Error("zero Glob matched, want no match")
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher string
+		pattern string
+		stack   bool
+		wantErr bool
+	}{
+		{name: "fingerprint message rule", matcher: MatchMessage, pattern: "*timeout*"},
+		{name: "stack module rule", matcher: MatchModule, pattern: "github.com/*", stack: true},
+		{name: "stack rule on message", matcher: MatchMessage, pattern: "x", stack: true, wantErr: true},
+		{name: "unknown matcher", matcher: "path", pattern: "x", wantErr: true},
+		{name: "blank pattern", matcher: MatchFile, pattern: "  ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRule(tt.matcher, tt.pattern, tt.stack)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRule error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+const goStack = `goroutine 1 [running]:
+github.com/acme/app/db.(*Pool).Get(0xc000012345)
+	/home/dev/app/db/pool.go:42 +0x1d
+github.com/lib/pq.(*conn).query(0xc0000a0000)
+	/go/pkg/mod/github.com/lib/pq@v1.10.9/conn.go:100 +0x2f
+main.main()
+	/home/dev/app/main.go:12 +0x25
+`
+
+func TestFingerprintRules(t *testing.T) {
+	stack := goStack
+
+	tests := []struct {
+		name     string
+		rules    []FingerprintRule
+		message  string
+		wantRule string
+	}{
+		{
+			name:     "message rule",
+			rules:    []FingerprintRule{NewFingerprintRule("r1", MatchMessage, "*connection refused*", []string{"db-down"})},
+			message:  "dial tcp: connection refused",
+			wantRule: "r1",
+		},
+		{
+			name:     "type rule",
+			rules:    []FingerprintRule{NewFingerprintRule("r1", MatchType, "*Timeout*", []string{"timeouts"})},
+			message:  "TimeoutError: upstream",
+			wantRule: "r1",
+		},
+		{
+			name:     "function rule matches any frame",
+			rules:    []FingerprintRule{NewFingerprintRule("r1", MatchFunction, "*(*conn).query", []string{"pq"})},
+			message:  "boom",
+			wantRule: "r1",
+		},
+		{
+			name: "first matching rule wins",
+			rules: []FingerprintRule{
+				NewFingerprintRule("r1", MatchFile, "*/nothing.go", []string{"a"}),
+				NewFingerprintRule("r2", MatchModule, "github.com/acme/*", []string{"b"}),
+				NewFingerprintRule("r3", MatchModule, "*", []string{"c"}),
+			},
+			message:  "boom",
+			wantRule: "r2",
+		},
+		{
+			name:    "no match",
+			rules:   []FingerprintRule{NewFingerprintRule("r1", MatchMessage, "other", []string{"x"})},
+			message: "boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := Input{Message: tt.message, StackTrace: &stack, Rules: Rules{Fingerprint: tt.rules}}
+			result := Explain(Stack, in)
+			if result.Rule != tt.wantRule {
+				t.Errorf("Rule = %q, want %q", result.Rule, tt.wantRule)
+			}
+
+			plain := Explain(Stack, Input{Message: tt.message, StackTrace: &stack})
+			if (result.Fingerprint != plain.Fingerprint) != (tt.wantRule != "") {
+				t.Errorf("fingerprint changed = %v, want %v", result.Fingerprint != plain.Fingerprint, tt.wantRule != "")
+			}
+		})
+	}
+}
+
+func TestStackRules(t *testing.T) {
+	stack := goStack
+
+	tests := []struct {
+		name  string
+		rules []StackRule
+		want  []bool
+	}{
+		{
+			name: "built-in guess",
+			want: []bool{true, false, true},
+		},
+		{
+			name:  "rule marks a module as not in-app",
+			rules: []StackRule{NewStackRule("r1", MatchModule, "github.com/acme/app/db", false)},
+			want:  []bool{false, false, true},
+		},
+		{
+			name:  "rule marks a dependency as in-app",
+			rules: []StackRule{NewStackRule("r1", MatchFile, "*/pq@*/conn.go", true)},
+			want:  []bool{true, true, true},
+		},
+		{
+			name: "last matching rule wins",
+			rules: []StackRule{
+				NewStackRule("r1", MatchFunction, "main.*", false),
+				NewStackRule("r2", MatchFunction, "main.main", true),
+			},
+			want: []bool{true, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := Input{StackTrace: &stack, Rules: Rules{Stack: tt.rules}}.Frames()
+			if len(frames) != len(tt.want) {
+				t.Fatalf("got %d frames, want %d", len(frames), len(tt.want))
+			}
+			for i, f := range frames {
+				if f.InApp != tt.want[i] {
+					t.Errorf("frame %d (%s) InApp = %v, want %v", i, f.Function, f.InApp, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSDKInAppFlag(t *testing.T) {
+	// As ingestion formats Sentry frames.
+	stack := "vendor.lib.call\n\t/srv/lib.py:3 in_app=true\napp.handler\n\t/srv/app.py:9 in_app=false\n"
+
+	frames := Input{StackTrace: &stack}.Frames()
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(frames))
+	}
+	if !frames[0].InApp || frames[1].InApp {
+		t.Errorf("InApp = %v, %v, want the SDK's true, false", frames[0].InApp, frames[1].InApp)
+	}
+	if frames[1].File != "/srv/app.py" || frames[1].Line != 9 {
+		t.Errorf("frame 1 = %s:%d, want /srv/app.py:9", frames[1].File, frames[1].Line)
+	}
+
+	frames = Input{StackTrace: &stack, Rules: Rules{Stack: []StackRule{NewStackRule("r1", MatchFile, "/srv/app.py", true)}}}.Frames()
+	if !frames[1].InApp {
+		t.Error("stack rule did not override the SDK flag")
+	}
+}
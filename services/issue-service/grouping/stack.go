@@ -0,0 +1,161 @@
+package grouping
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Frame is one call in a stack trace.
+type Frame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	InApp    bool   `json:"in_app"`
+
+	// sdkInApp is the in-app flag the reporting SDK sent, if any.
+	sdkInApp *bool
+}
+
+// inAppMarker follows the "file:line" of a frame whose SDK said whether it
+// is in-app, e.g. "\tapp/main.go:12 in_app=false". Ingestion writes it for
+// Sentry events.
+const inAppMarker = "in_app="
+
+var (
+	// "at fn (file:line:col)" or "at file:line", as printed by JavaScript
+	// and JVM runtimes.
+	atFrame = regexp.MustCompile(`^at (?:(.+?) \()?(.+?)(?::(\d+))?(?::\d+)?\)?$`)
+
+	// Trailing argument list of a Go frame, e.g. "(0xc000012345, 0x1)".
+	goArgs = regexp.MustCompile(`\([^()]*\)$`)
+
+	// Go names anonymous functions func1, func2... after their position,
+	// which shifts as code is edited.
+	closure = regexp.MustCompile(`\.func\d+`)
+)
+
+// ParseStack parses a stack trace in Go format (a function line followed by
+// a tab-indented "file:line" line, innermost call first) or "at ..." format.
+// Lines in neither format become frames with just a function name.
+func ParseStack(stack string) []Frame {
+	var frames []Frame
+
+	lines := strings.Split(stack, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "",
+			strings.HasPrefix(trimmed, "goroutine "),
+			strings.HasPrefix(trimmed, "panic:"),
+			strings.HasPrefix(trimmed, "[recovered]"):
+			continue
+
+		case atFrame.MatchString(trimmed):
+			m := atFrame.FindStringSubmatch(trimmed)
+			f := Frame{Function: m[1], File: m[2]}
+			f.Line, _ = strconv.Atoi(m[3])
+			frames = append(frames, f)
+
+		case strings.HasPrefix(line, "\t") && len(frames) > 0 && frames[len(frames)-1].File == "":
+			last := &frames[len(frames)-1]
+			last.File, last.Line, last.sdkInApp = fileLine(trimmed)
+
+		default:
+			// "created by" names the function that started the goroutine.
+			function := strings.TrimPrefix(trimmed, "created by ")
+			if i := strings.Index(function, " in goroutine "); i >= 0 {
+				function = function[:i]
+			}
+
+			f := Frame{Function: goArgs.ReplaceAllString(function, "")}
+			if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+				f.File, f.Line, f.sdkInApp = fileLine(strings.TrimSpace(lines[i+1]))
+				i++
+			}
+			frames = append(frames, f)
+		}
+	}
+
+	for i := range frames {
+		frames[i].Function = closure.ReplaceAllString(frames[i].Function, ".func")
+		frames[i].Module = module(frames[i].Function)
+		if frames[i].sdkInApp != nil {
+			frames[i].InApp = *frames[i].sdkInApp
+		} else {
+			frames[i].InApp = inApp(frames[i])
+		}
+	}
+
+	return frames
+}
+
+// fileLine splits "path/file.go:42 +0x1d", returning the in-app flag when
+// the line carries an inAppMarker.
+func fileLine(s string) (string, int, *bool) {
+	var sdkInApp *bool
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		for _, field := range strings.Fields(s[i+1:]) {
+			if v, ok := strings.CutPrefix(field, inAppMarker); ok {
+				if b, err := strconv.ParseBool(v); err == nil {
+					sdkInApp = &b
+				}
+			}
+		}
+		s = s[:i]
+	}
+
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return s, 0, sdkInApp
+	}
+
+	line, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return s, 0, sdkInApp
+	}
+	return s[:i], line, sdkInApp
+}
+
+// module returns the package path of a Go function name such as
+// "github.com/acme/app/db.(*Pool).Get", or everything before the last dot
+// for other languages.
+func module(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	if dot := strings.IndexByte(function[slash+1:], '.'); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return ""
+}
+
+// inApp guesses whether a frame belongs to the application rather than the
+// Go runtime, the standard library or a dependency, for frames whose SDK did
+// not say. Projects can override the guess with stack rules.
+func inApp(f Frame) bool {
+	switch {
+	case strings.Contains(f.File, "/pkg/mod/"),
+		strings.Contains(f.File, "/vendor/"),
+		strings.Contains(f.File, "/node_modules/"):
+		return false
+	}
+
+	if f.Module == "" || f.Module == "main" {
+		return true
+	}
+
+	// Standard library packages have no dot in their first path element.
+	first, _, _ := strings.Cut(f.Module, "/")
+	if strings.Contains(first, ".") {
+		return true
+	}
+
+	// A local module can also lack a dot; GOROOT sources live under
+	// .../src/<package>/.
+	if f.File != "" {
+		return !strings.Contains(f.File, "/src/"+f.Module+"/")
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package grouping
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Quoted values. A single quote only opens a value after a space or
+	// punctuation, so apostrophes ("can't") are left alone.
+	quoted = regexp.MustCompile("\"[^\"]*\"|`[^`]*`|(?:^|[\\s(\\[{=:,])'[^']*'")
+	uuidRe = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexRe  = regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]{8,}\b`)
+	number = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	spaces = regexp.MustCompile(`\s+`)
+)
+
+// Template replaces the variable parts of a message with placeholders, so
+// "user 123 not found" and "user 456 not found" read "user <num> not found".
+// Quoted values, UUIDs, hex values of 8 or more characters and numbers are
+// replaced.
+func Template(message string) string {
+	s := quoted.ReplaceAllStringFunc(message, func(m string) string {
+		// Keep the delimiter matched before a single-quoted value.
+		if m[0] != '"' && m[0] != '`' && m[0] != '\'' {
+			return m[:1] + "<str>"
+		}
+		return "<str>"
+	})
+
+	s = uuidRe.ReplaceAllString(s, "<uuid>")
+
+	s = hexRe.ReplaceAllStringFunc(s, func(m string) string {
+		// Words made only of the letters a-f ("defaced") are not values.
+		if strings.HasPrefix(strings.ToLower(m), "0x") || strings.ContainsAny(m, "0123456789") {
+			return "<hex>"
+		}
+		return m
+	})
+
+	s = number.ReplaceAllString(s, "<num>")
+
+	return strings.TrimSpace(spaces.ReplaceAllString(s, " "))
+}
+
+// ExceptionType returns the type of a "Type: value" message, the shape
+// ingestion gives events translated from exceptions, or "" when the message
+// does not start with one.
+func ExceptionType(message string) string {
+	typ, _, ok := strings.Cut(message, ": ")
+	if !ok || typ == "" || len(typ) > 128 || strings.ContainsAny(typ, " \t\n") {
+		return ""
+	}
+	return typ
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/k1ngalph0x/beacon/services/issue-service/models"
+	"gorm.io/gorm"
+)
+
+// Activity types. Status changes are logged under their transition name
+// (resolved, ignored, reopened, regressed, unignored).
+const (
+	ActivityFirstSeen  = "first_seen"
+	ActivityAssigned   = "assigned"
+	ActivityUnassigned = "unassigned"
+	ActivityMerged     = "merged"
+	ActivityMergedInto = "merged_into"
+	ActivityUnmerged   = "unmerged"
+)
+
+const maxCommentLength = 10000
+
+type CreateCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// recordActivity appends to an issue's activity log. An empty userID means
+// issue-service made the change.
+func recordActivity(tx *gorm.DB, issue *models.Issue, activityType, userID string, data map[string]interface{}) error {
+	activity := models.IssueActivity{
+		IssueID:   issue.ID,
+		ProjectID: issue.ProjectID,
+		Type:      activityType,
+		Data:      data,
+	}
+	if userID != "" {
+		activity.UserID = &userID
+	}
+
+	return tx.Create(&activity).Error
+}
+
+// GetIssueActivity lists an issue's activity, newest first.
+func GetIssueActivity(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var activity []models.IssueActivity
+
+		issue, ok := loadOwnedIssue(c, db)
+		if !ok {
+			return
+		}
+
+		limit, ok := pageLimit(c)
+		if !ok {
+			return
+		}
+
+		query := db.Where("issue_id = ?", issue.ID)
+
+		if cursor := c.Query("cursor"); cursor != "" {
+			ts, id, err := parseTimeCursor(cursor)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid cursor"})
+				return
+			}
+			query = query.Where("(created_at, id) < (?, ?)", ts, id)
+		}
+
+		err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&activity).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		var next string
+		if len(activity) > limit {
+			activity = activity[:limit]
+			next = timeCursor(activity[limit-1].CreatedAt, activity[limit-1].ID)
+		}
+
+		c.JSON(200, gin.H{"activity": activity, "next_cursor": next})
+	}
+}
+
+// GetIssueComments lists an issue's comments, oldest first.
+func GetIssueComments(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var comments []models.IssueComment
+
+		issue, ok := loadOwnedIssue(c, db)
+		if !ok {
+			return
+		}
+
+		limit, ok := pageLimit(c)
+		if !ok {
+			return
+		}
+
+		query := db.Where("issue_id = ?", issue.ID)
+
+		if cursor := c.Query("cursor"); cursor != "" {
+			ts, id, err := parseTimeCursor(cursor)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid cursor"})
+				return
+			}
+			query = query.Where("(created_at, id) > (?, ?)", ts, id)
+		}
+
+		err := query.Order("created_at ASC, id ASC").Limit(limit + 1).Find(&comments).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		var next string
+		if len(comments) > limit {
+			comments = comments[:limit]
+			next = timeCursor(comments[limit-1].CreatedAt, comments[limit-1].ID)
+		}
+
+		c.JSON(200, gin.H{"comments": comments, "next_cursor": next})
+	}
+}
+
+func CreateIssueComment(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateCommentRequest
+
+		issue, ok := loadOwnedIssue(c, db)
+		if !ok {
+			return
+		}
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil || strings.TrimSpace(req.Body) == "" {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		if len(req.Body) > maxCommentLength {
+			c.JSON(400, gin.H{"error": "Comment is too long"})
+			return
+		}
+
+		comment := models.IssueComment{
+			IssueID: issue.ID,
+			UserID:  c.GetString("user_id"),
+			Body:    req.Body,
+		}
+
+		err = db.Create(&comment).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to create comment"})
+			return
+		}
+
+		c.JSON(201, gin.H{"comment": comment})
+	}
+}
+
+// DeleteIssueComment deletes a comment. Only its author can delete it.
+func DeleteIssueComment(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		issue, ok := loadOwnedIssue(c, db)
+		if !ok {
+			return
+		}
+
+		commentID := c.Param("comment_id")
+		if _, err := uuid.Parse(commentID); err != nil {
+			c.JSON(404, gin.H{"error": "Comment not found"})
+			return
+		}
+
+		result := db.Where("id = ? AND issue_id = ? AND user_id = ?", commentID, issue.ID, c.GetString("user_id")).
+			Delete(&models.IssueComment{})
+		if result.Error != nil {
+			c.JSON(500, gin.H{"error": "Failed to delete comment"})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(404, gin.H{"error": "Comment not found"})
+			return
+		}
+
+		c.JSON(200, gin.H{"message": "Comment deleted"})
+	}
+}
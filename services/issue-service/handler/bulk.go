@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/k1ngalph0x/beacon/services/issue-service/models"
+	publisher "github.com/k1ngalph0x/beacon/services/issue-service/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Most issues one bulk update may touch.
+const maxBulkIssues = 1000
+
+// BulkUpdateRequest selects issues either by ID or by a search query over
+// projects, and applies exactly one change to all of them.
+type BulkUpdateRequest struct {
+	IssueIDs   []string `json:"issue_ids"`
+	ProjectIDs []string `json:"project_ids"`
+	Query      *string  `json:"query"`
+
+	Status *UpdateStatusRequest `json:"status"`
+	Assign *AssignIssueRequest  `json:"assign"`
+	Delete bool                 `json:"delete"`
+}
+
+var errBulkConflict = errors.New("issues changed during bulk update")
+
+func validBulkRequest(req BulkUpdateRequest) error {
+	if (len(req.IssueIDs) == 0) == (req.Query == nil) {
+		return errors.New("either issue_ids or query is required")
+	}
+	if req.Query != nil && len(req.ProjectIDs) == 0 {
+		return errors.New("project_ids is required with query")
+	}
+	if len(req.IssueIDs) > maxBulkIssues {
+		return errors.New("too many issue_ids")
+	}
+	for _, id := range req.IssueIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return errors.New("issue_ids must be issue IDs")
+		}
+	}
+
+	changes := 0
+	for _, set := range []bool{req.Status != nil, req.Assign != nil, req.Delete} {
+		if set {
+			changes++
+		}
+	}
+	if changes != 1 {
+		return errors.New("exactly one of status, assign or delete is required")
+	}
+
+	if req.Status != nil {
+		return validStatusRequest(*req.Status)
+	}
+	return nil
+}
+
+// selectBulkIssues resolves the issues a bulk update applies to. It writes
+// the error response itself and returns false on failure.
+func selectBulkIssues(c *gin.Context, db *gorm.DB, req BulkUpdateRequest, userID string) ([]string, bool) {
+	var issues []models.Issue
+
+	if req.Query != nil {
+		for _, projectID := range req.ProjectIDs {
+			if !verifyProjectOwnership(db, userID, projectID) {
+				c.JSON(403, gin.H{"error": "Forbidden", "project_id": projectID})
+				return nil, false
+			}
+		}
+
+		tx, err := issueSearchQuery(db, req.ProjectIDs, *req.Query, userID)
+		var searchErr *SearchError
+		if errors.As(err, &searchErr) {
+			c.JSON(400, gin.H{"error": searchErr.Message})
+			return nil, false
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return nil, false
+		}
+
+		err = tx.Order("issues.id").Limit(maxBulkIssues + 1).Find(&issues).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return nil, false
+		}
+		if len(issues) > maxBulkIssues {
+			c.JSON(400, gin.H{"error": "Query matches too many issues, narrow it down"})
+			return nil, false
+		}
+	} else {
+		ids := uniqueStrings(req.IssueIDs)
+
+		err := db.Where("id IN ?", ids).Find(&issues).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return nil, false
+		}
+		if len(issues) != len(ids) {
+			c.JSON(404, gin.H{"error": "Issue not found"})
+			return nil, false
+		}
+
+		verified := map[string]bool{}
+		for _, issue := range issues {
+			if issue.MergedIntoID != nil {
+				c.JSON(409, gin.H{"error": "Issue is merged", "issue_id": issue.ID})
+				return nil, false
+			}
+			if verified[issue.ProjectID] {
+				continue
+			}
+			if !verifyProjectOwnership(db, userID, issue.ProjectID) {
+				c.JSON(403, gin.H{"error": "Forbidden", "project_id": issue.ProjectID})
+				return nil, false
+			}
+			verified[issue.ProjectID] = true
+		}
+	}
+
+	ids := make([]string, len(issues))
+	for i, issue := range issues {
+		ids[i] = issue.ID
+	}
+	return ids, true
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// deleteIssues removes issues along with everything recorded about them,
+// including issues merged into them. Their activity logs are kept, ending
+// with the deletion, as an audit trail. Events arriving later for their
+// fingerprints start new issues.
+func deleteIssues(tx *gorm.DB, issues []models.Issue, userID string) error {
+	var ids []string
+	for _, issue := range issues {
+		ids = append(ids, issue.ID)
+	}
+
+	var merged []models.Issue
+	err := tx.Where("merged_into_id IN ?", ids).Find(&merged).Error
+	if err != nil {
+		return err
+	}
+
+	var all []string
+	for _, issue := range append(append([]models.Issue{}, issues...), merged...) {
+		all = append(all, issue.ID)
+		err = recordActivity(tx, &issue, TransitionDeleted, userID, map[string]interface{}{
+			"from": issue.Status,
+			"to":   TransitionDeleted,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Stored events outlive their issue.
+	err = moveStoredEvents(tx, nil, "issue_id IN ?", all)
+	if err != nil {
+		return err
+	}
+
+	for _, model := range []interface{}{
+		&models.IssueFingerprint{},
+		&models.IssueEvent{},
+		&models.IssueUser{},
+		&models.IssueTag{},
+		&models.IssueComment{},
+	} {
+		err = tx.Where("issue_id IN ?", all).Delete(model).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Where("id IN ?", all).Delete(&models.Issue{}).Error
+}
+
+// BulkUpdateIssues changes the status or assignee of many issues, or
+// deletes them, in one transaction. Every changed issue publishes the same
+// events as a single update would.
+func BulkUpdateIssues(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BulkUpdateRequest
+		var issues []models.Issue
+		var stateChanges []*IssueStateChangeEvent
+		var assignments []*IssueAssignmentEvent
+		var resolutions []IssueResolvedEvent
+
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.JSON(401, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		err = validBulkRequest(req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		var assignee *string
+		if req.Assign != nil {
+			assignee, err = normalizeAssignee(db, req.Assign.Assignee, userID)
+			if err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		ids, ok := selectBulkIssues(c, db, req, userID)
+		if !ok {
+			return
+		}
+		if len(ids) == 0 {
+			c.JSON(200, gin.H{"issue_ids": ids, "changed": 0})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id IN ? AND merged_into_id IS NULL", ids).
+				Order("id").
+				Find(&issues).Error
+			if err != nil {
+				return err
+			}
+			if len(issues) != len(ids) {
+				return errBulkConflict
+			}
+
+			if req.Delete {
+				return deleteIssues(tx, issues, userID)
+			}
+
+			for i := range issues {
+				if req.Assign != nil {
+					assignment, err := setAssignee(tx, &issues[i], assignee, userID)
+					if err != nil {
+						return err
+					}
+					if assignment != nil {
+						assignments = append(assignments, assignment)
+					}
+					continue
+				}
+
+				change, err := setStatus(tx, &issues[i], StatusChange{
+					Status:          req.Status.Status,
+					Release:         req.Status.InRelease,
+					IgnoreUntil:     req.Status.IgnoreUntil,
+					IgnoreCount:     req.Status.IgnoreCount,
+					IgnoreUserCount: req.Status.IgnoreUserCount,
+					By:              userID,
+				})
+				if err != nil {
+					return err
+				}
+				if change != nil {
+					stateChanges = append(stateChanges, change)
+				}
+				if change != nil && change.Transition == TransitionResolved {
+					resolutions = append(resolutions, IssueResolvedEvent{
+						IssueID:    issues[i].ID,
+						ProjectID:  issues[i].ProjectID,
+						ResolvedAt: *issues[i].ResolvedAt,
+						Release:    issues[i].ResolvedInRelease,
+					})
+				}
+			}
+			return nil
+		})
+
+		if errors.Is(err, errBulkConflict) {
+			c.JSON(409, gin.H{"error": "Issues changed during the update, retry"})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to update issues"})
+			return
+		}
+
+		now := time.Now()
+		if req.Delete {
+			for _, issue := range issues {
+				publisher.PublishEvent(issueStateWriter, issue.ProjectID, IssueStateChangeEvent{
+					IssueID:    issue.ID,
+					ProjectID:  issue.ProjectID,
+					Transition: TransitionDeleted,
+					From:       issue.Status,
+					To:         TransitionDeleted,
+					ChangedAt:  now,
+				})
+			}
+			c.JSON(200, gin.H{"issue_ids": ids, "changed": len(issues)})
+			return
+		}
+
+		for _, change := range stateChanges {
+			publisher.PublishEvent(issueStateWriter, change.ProjectID, change)
+		}
+		for _, resolution := range resolutions {
+			publisher.PublishEvent(issueResolvedWriter, resolution.ProjectID, resolution)
+		}
+		for _, assignment := range assignments {
+			publisher.PublishEvent(issueAssignmentWriter, assignment.ProjectID, assignment)
+		}
+
+		c.JSON(200, gin.H{"issue_ids": ids, "changed": len(stateChanges) + len(assignments)})
+	}
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	eventsTopic     = "beacon-events"
+	deadLetterTopic = "beacon-events-dlq"
+)
+
+// Headers added to dead-lettered messages. The key and value are kept as
+// they were, so a replay publishes the original event.
+const (
+	HeaderError     = "x-beacon-error"
+	HeaderTopic     = "x-beacon-topic"
+	HeaderPartition = "x-beacon-partition"
+	HeaderOffset    = "x-beacon-offset"
+	HeaderAttempts  = "x-beacon-attempts"
+	HeaderFailedAt  = "x-beacon-failed-at"
+)
+
+const (
+	defaultReplayLimit = 1000
+	maxReplayLimit     = 10000
+	// Joining the replay consumer group can take a few seconds; after the
+	// first message, a short wait means the topic is drained.
+	replayJoinTimeout = 15 * time.Second
+	replayIdleTimeout = 2 * time.Second
+)
+
+var deadLetterWriter = &kafka.Writer{
+	Addr:     kafka.TCP("localhost:9092"),
+	Topic:    deadLetterTopic,
+	Balancer: &kafka.LeastBytes{},
+}
+
+var eventsWriter = &kafka.Writer{
+	Addr:     kafka.TCP("localhost:9092"),
+	Topic:    eventsTopic,
+	Balancer: &kafka.LeastBytes{},
+}
+
+// brokers are the Kafka brokers the service reads from and writes to.
+var brokers = []string{"localhost:9092"}
+
+// SetBrokers points the service's Kafka readers and writers at addrs. It
+// must be called before any of them is used.
+func SetBrokers(addrs []string) {
+	brokers = addrs
+	for _, w := range []*kafka.Writer{
+		issueUpdateWriter,
+		issueResolvedWriter,
+		issueStateWriter,
+		issueAssignmentWriter,
+		deadLetterWriter,
+		eventsWriter,
+	} {
+		w.Addr = kafka.TCP(addrs...)
+	}
+}
+
+// replaying serialises replays: concurrent readers in the replay group
+// would rebalance its partitions between each other mid-replay.
+var replaying sync.Mutex
+
+type ReplayRequest struct {
+	// Most messages to replay; defaults to 1000.
+	Limit int `json:"limit"`
+}
+
+// DeadLetter moves a message that cannot be processed to the dead-letter
+// topic, with the reason it failed. The message's offset must not be
+// committed unless it succeeds.
+func DeadLetter(msg kafka.Message, reason string, attempts int) error {
+	letter := kafka.Message{
+		Key:   msg.Key,
+		Value: msg.Value,
+		Headers: []kafka.Header{
+			{Key: HeaderError, Value: []byte(reason)},
+			{Key: HeaderTopic, Value: []byte(msg.Topic)},
+			{Key: HeaderPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+			{Key: HeaderOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+			{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+			{Key: HeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+		},
+	}
+
+	err := deadLetterWriter.WriteMessages(context.Background(), letter)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Dead-lettered message %s/%d@%d: %s", msg.Topic, msg.Partition, msg.Offset, reason)
+	return nil
+}
+
+// ReplayDeadLetters publishes dead-lettered events back to beacon-events,
+// oldest first, and commits each once it is published. Events failing again
+// return to the dead-letter topic. One replay runs at a time.
+func ReplayDeadLetters() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ReplayRequest
+
+		if !replaying.TryLock() {
+			c.JSON(409, gin.H{"error": "A replay is already running"})
+			return
+		}
+		defer replaying.Unlock()
+
+		err := c.ShouldBindJSON(&req)
+		if (err != nil && !errors.Is(err, io.EOF)) || req.Limit < 0 {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		limit := req.Limit
+		if limit == 0 {
+			limit = defaultReplayLimit
+		}
+		limit = min(limit, maxReplayLimit)
+
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers: brokers,
+			Topic:   deadLetterTopic,
+			GroupID: "issue-dlq-replay",
+		})
+		defer reader.Close()
+
+		replayed := 0
+		timeout := replayJoinTimeout
+		for replayed < limit {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+			msg, err := reader.FetchMessage(ctx)
+			cancel()
+			if errors.Is(err, context.DeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				log.Println("Failed to read dead letters:", err)
+				c.JSON(502, gin.H{"error": "Failed to read dead letters", "replayed": replayed})
+				return
+			}
+			timeout = replayIdleTimeout
+
+			err = eventsWriter.WriteMessages(c.Request.Context(), kafka.Message{Key: msg.Key, Value: msg.Value})
+			if err != nil {
+				log.Println("Failed to replay dead letter:", err)
+				c.JSON(502, gin.H{"error": "Failed to replay dead letters", "replayed": replayed})
+				return
+			}
+
+			err = reader.CommitMessages(c.Request.Context(), msg)
+			if err != nil {
+				// Published but not committed: the next replay repeats it,
+				// which event de-duplication absorbs.
+				log.Println("Failed to commit dead letter:", err)
+				c.JSON(502, gin.H{"error": "Failed to commit dead letters", "replayed": replayed + 1})
+				return
+			}
+			replayed++
+		}
+
+		c.JSON(200, gin.H{"replayed": replayed})
+	}
+}
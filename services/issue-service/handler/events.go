@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/k1ngalph0x/beacon/services/issue-service/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// IssueEventDetail is an event of an issue joined with the row kafka-service
+// stored for it. The stored fields are empty until kafka-service has caught
+// up.
+type IssueEventDetail struct {
+	EventID     string     `json:"event_id"`
+	Fingerprint string     `json:"fingerprint"`
+	Timestamp   time.Time  `json:"timestamp"`
+	Level       string     `json:"level"`
+	Message     string     `json:"message"`
+	StackTrace  *string    `json:"stack_trace,omitempty"`
+	Environment string     `json:"environment,omitempty"`
+	Release     string     `json:"release,omitempty"`
+	ReceivedAt  *time.Time `json:"received_at,omitempty"`
+}
+
+// timeCursor encodes a position in a list ordered by time and ID, such as
+// the events, comments or activity of an issue.
+func timeCursor(t time.Time, id string) string {
+	raw := t.UTC().Format(time.RFC3339Nano) + "," + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseTimeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	ts, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return time.Time{}, "", errors.New("malformed cursor")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	return t, id, err
+}
+
+// storedEvents is Kafka Service's table of stored events. Each row records
+// the issue its event was grouped into.
+const storedEvents = "events"
+
+// moveStoredEvents points stored events matching a condition at another
+// issue; a nil issueID unlinks them. It does nothing before Kafka Service
+// has created its table.
+func moveStoredEvents(tx *gorm.DB, issueID *string, query string, args ...interface{}) error {
+	if !tx.Migrator().HasTable(storedEvents) {
+		return nil
+	}
+	return tx.Table(storedEvents).Where(query, args...).Update("issue_id", issueID).Error
+}
+
+// issueEventsQuery selects the events of an issue as IssueEventDetail. Before
+// Kafka Service has created its table, only the fields of issue_events are
+// filled in.
+func issueEventsQuery(db *gorm.DB, issueID string) *gorm.DB {
+	query := db.Table("issue_events").Where("issue_events.issue_id = ?", issueID)
+	if !db.Migrator().HasTable(storedEvents) {
+		return query.Select("issue_events.event_id, issue_events.fingerprint, issue_events.timestamp")
+	}
+
+	return query.
+		Select(`issue_events.event_id, issue_events.fingerprint, issue_events.timestamp,
+			events.level, events.message, events.stack_trace, events.environment, events.release, events.received_at`).
+		Joins("LEFT JOIN events ON events.project_id = issue_events.project_id AND events.id = issue_events.event_id")
+}
+
+// pageLimit reads the limit query parameter of a paginated list.
+func pageLimit(c *gin.Context) (int, bool) {
+	limit := defaultPageSize
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(400, gin.H{"error": "Invalid limit"})
+			return 0, false
+		}
+		limit = min(n, maxPageSize)
+	}
+	return limit, true
+}
+
+// loadOwnedIssue loads the issue named by the :id parameter and checks that
+// the user owns its project, writing the error response when not.
+func loadOwnedIssue(c *gin.Context, db *gorm.DB) (*models.Issue, bool) {
+	var issue models.Issue
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(401, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	err := db.First(&issue, "id = ?", c.Param("id")).Error
+	if err != nil {
+		c.JSON(404, gin.H{"error": "Issue not found"})
+		return nil, false
+	}
+
+	if !verifyProjectOwnership(db, userID, issue.ProjectID) {
+		c.JSON(403, gin.H{"error": "Forbidden"})
+		return nil, false
+	}
+
+	return &issue, true
+}
+
+// GetIssueEvents lists the events of an issue, newest first. Pages are
+// requested with the next_cursor of the previous page.
+func GetIssueEvents(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var events []IssueEventDetail
+
+		issue, ok := loadOwnedIssue(c, db)
+		if !ok {
+			return
+		}
+
+		limit, ok := pageLimit(c)
+		if !ok {
+			return
+		}
+
+		query := issueEventsQuery(db, issue.ID)
+
+		if cursor := c.Query("cursor"); cursor != "" {
+			ts, id, err := parseTimeCursor(cursor)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid cursor"})
+				return
+			}
+			query = query.Where("(issue_events.timestamp, issue_events.event_id) < (?, ?)", ts, id)
+		}
+
+		// One extra row tells whether there is another page.
+		err := query.Order("issue_events.timestamp DESC, issue_events.event_id DESC").Limit(limit + 1).Scan(&events).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		var next string
+		if len(events) > limit {
+			events = events[:limit]
+			next = timeCursor(events[limit-1].Timestamp, events[limit-1].EventID)
+		}
+
+		c.JSON(200, gin.H{"events": events, "next_cursor": next})
+	}
+}
+
+func issueEventAt(db *gorm.DB, order string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var events []IssueEventDetail
+
+		issue, ok := loadOwnedIssue(c, db)
+		if !ok {
+			return
+		}
+
+		err := issueEventsQuery(db, issue.ID).Order(order).Limit(1).Scan(&events).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		if len(events) == 0 {
+			c.JSON(404, gin.H{"error": "Issue has no stored events"})
+			return
+		}
+
+		c.JSON(200, gin.H{"event": events[0]})
+	}
+}
+
+func GetLatestIssueEvent(db *gorm.DB) gin.HandlerFunc {
+	return issueEventAt(db, "issue_events.timestamp DESC, issue_events.event_id DESC")
+}
+
+func GetOldestIssueEvent(db *gorm.DB) gin.HandlerFunc {
+	return issueEventAt(db, "issue_events.timestamp ASC, issue_events.event_id ASC")
+}
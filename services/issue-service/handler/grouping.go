@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/k1ngalph0x/beacon/services/issue-service/grouping"
+	"github.com/k1ngalph0x/beacon/services/issue-service/models"
+	"gorm.io/gorm"
+)
+
+type UpdateGroupingRequest struct {
+	GroupingConfig string `json:"grouping_config" binding:"required"`
+}
+
+// loadProjectSettings returns the settings of a project, or the defaults
+// when it has never changed them.
+func loadProjectSettings(db *gorm.DB, projectID string) (models.ProjectSettings, error) {
+	var settings models.ProjectSettings
+
+	err := db.First(&settings, "project_id = ?", projectID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ProjectSettings{ProjectID: projectID, GroupingConfig: grouping.Default}, nil
+	}
+	return settings, err
+}
+
+func GetGroupingSettings(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		projectID := c.Param("project_id")
+		userID := c.GetString("user_id")
+
+		if !verifyProjectOwnership(db, userID, projectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		settings, err := loadProjectSettings(db, projectID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"settings":         settings,
+			"grouping_configs": grouping.Configs(),
+		})
+	}
+}
+
+// UpdateGroupingSettings switches a project to another grouping config. For
+// the transition period events that would otherwise open a new issue are
+// also matched against the fingerprints of the previous config.
+func UpdateGroupingSettings(db *gorm.DB, transition time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UpdateGroupingRequest
+
+		projectID := c.Param("project_id")
+		userID := c.GetString("user_id")
+
+		if !verifyProjectOwnership(db, userID, projectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil || !grouping.Valid(req.GroupingConfig) {
+			c.JSON(400, gin.H{"error": "Invalid grouping config", "grouping_configs": grouping.Configs()})
+			return
+		}
+
+		settings, err := loadProjectSettings(db, projectID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		if settings.GroupingConfig != req.GroupingConfig {
+			until := time.Now().Add(transition)
+			settings.PreviousGroupingConfig = settings.GroupingConfig
+			settings.TransitionUntil = &until
+			settings.GroupingConfig = req.GroupingConfig
+
+			err = db.Save(&settings).Error
+			if err != nil {
+				c.JSON(500, gin.H{"error": "Failed to update settings"})
+				return
+			}
+		}
+
+		c.JSON(200, gin.H{"settings": settings})
+	}
+}
@@ -1,17 +1,23 @@
 package handler
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/k1ngalph0x/beacon/services/issue-service/grouping"
 	"github.com/k1ngalph0x/beacon/services/issue-service/models"
 	publisher "github.com/k1ngalph0x/beacon/services/issue-service/utils"
+	"github.com/lib/pq"
 	"github.com/segmentio/kafka-go"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 
@@ -23,13 +29,20 @@ type IssueUpdateEvent struct {
 	Count     int       `json:"count"`
 	Level     string    `json:"level"`
 	Status    string    `json:"status"`
+	Assignee  *string   `json:"assignee,omitempty"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type ResolveIssueRequest struct {
+	InRelease     string `json:"in_release"`
+	InNextRelease bool   `json:"in_next_release"`
+}
+
 type IssueResolvedEvent struct {
 	IssueID   string    `json:"issue_id"`
 	ProjectID string    `json:"project_id"`
 	ResolvedAt time.Time `json:"resolved_at"`
+	Release   string    `json:"release,omitempty"`
 }
 
 
@@ -47,14 +60,21 @@ var issueUpdateWriter = &kafka.Writer{
 }
 
 
-func generateFingerprint(message string, stack *string) string{
-	raw := message
-	if stack != nil{
-		raw += "|" + *stack
+// fingerprints returns the fingerprint of an event under the project's
+// grouping config and rules, followed by its fingerprint under the previous
+// config while a config change is in transition.
+func fingerprints(settings models.ProjectSettings, rules grouping.Rules, e models.Event) []string {
+	input := grouping.Input{Message: e.Message, StackTrace: e.StackTrace, Fingerprint: e.Fingerprint, Rules: rules}
+	fps := []string{grouping.Fingerprint(settings.GroupingConfig, input)}
+
+	if settings.PreviousGroupingConfig != "" && settings.TransitionUntil != nil && time.Now().Before(*settings.TransitionUntil) {
+		previous := grouping.Fingerprint(settings.PreviousGroupingConfig, input)
+		if previous != fps[0] {
+			fps = append(fps, previous)
+		}
 	}
 
-	hash := sha256.Sum256([]byte(raw))
-	return hex.EncodeToString(hash[:])
+	return fps
 }
 
 
@@ -70,10 +90,11 @@ func verifyProjectOwnership(db *gorm.DB, userID, projectID string) bool{
 	return count > 0
 }
 
+// GetProjectIssue searches a project's issues. The query parameter takes
+// the search language, sort one of last_seen, first_seen, count or
+// user_count, and cursor the next_cursor of the previous page.
 func GetProjectIssue(db *gorm.DB) gin.HandlerFunc{
 	return func(c *gin.Context){
-		var issues []models.Issue
-		
 		projectID := c.Param("project_id")
 		userID := c.GetString("user_id")
 
@@ -87,28 +108,53 @@ func GetProjectIssue(db *gorm.DB) gin.HandlerFunc{
 			return
 		}
 
-		err := db.Where("project_id = ?", projectID).Order("last_seen desc").Find(&issues)
+		limit := 0
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				c.JSON(400, gin.H{"error": "Invalid limit"})
+				return
+			}
+			limit = n
+		}
+
+		page, err := searchIssues(db, []string{projectID}, c.Query("query"), c.Query("sort"), c.Query("cursor"), limit, userID)
+
+		var searchErr *SearchError
+		if errors.As(err, &searchErr) {
+			c.JSON(400, gin.H{"error": searchErr.Message})
+			return
+		}
 		if err != nil {
 			c.JSON(500, gin.H{"error": "Internal server error"})
 			return
 		}
 
-		c.JSON(200, gin.H{"issues": issues}) 
+		c.JSON(200, gin.H{"issues": page.Issues, "next_cursor": page.NextCursor}) 
 	}
 }
 
 
+// ResolveIssue resolves an issue, optionally in a given release or in the
+// next release. The body is optional.
 func ResolveIssue(db *gorm.DB) gin.HandlerFunc{
 	return func(c *gin.Context){
 		var issue models.Issue
+		var req ResolveIssueRequest
 		userID := c.GetString("user_id")
 		if userID == "" {
 			c.JSON(401, gin.H{"error": "Unauthorized"})
 			return
 		}
+
+		err := c.ShouldBindJSON(&req)
+		if (err != nil && !errors.Is(err, io.EOF)) || (req.InRelease != "" && req.InNextRelease) {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
 		
 		id := c.Param("id")
-		err := db.First(&issue, "id = ?", id).Error; 
+		err = db.First(&issue, "id = ?", id).Error; 
 		if err != nil {
 			c.JSON(404, gin.H{"error": "Issue not found"})
 			return
@@ -119,17 +165,30 @@ func ResolveIssue(db *gorm.DB) gin.HandlerFunc{
 			return
 		}
 
-		issue.Status = "resolved"
-		result := db.Save(&issue)
-		if result.Error != nil{
+		change := StatusChange{Status: StatusResolved, Release: req.InRelease, By: userID}
+		if req.InNextRelease {
+			change = StatusChange{Status: StatusResolvedInNextRelease, By: userID}
+		}
+
+		var stateChange *IssueStateChangeEvent
+		err = db.Transaction(func(tx *gorm.DB) error {
+			stateChange, err = setStatus(tx, &issue, change)
+			return err
+		})
+		if err != nil{
 			c.JSON(500, gin.H{"error": "Failed to update issue"})
 			return
 		}
 
+		if stateChange != nil {
+			publisher.PublishEvent(issueStateWriter, issue.ProjectID, stateChange)
+		}
+
 		resolvedEvent := IssueResolvedEvent{
 			IssueID:   issue.ID,
 			ProjectID: issue.ProjectID,	
 			ResolvedAt: time.Now(),
+			Release:   issue.ResolvedInRelease,
 		}
 
 		publisher.PublishEvent(issueResolvedWriter, issue.ProjectID, resolvedEvent)
@@ -144,80 +203,548 @@ func ResolveIssue(db *gorm.DB) gin.HandlerFunc{
 
 func GetIssue(db *gorm.DB) gin.HandlerFunc{
 	return func(c *gin.Context){
-		id := c.Param("id")
-
-		var issue models.Issue
+		issue, ok := loadOwnedIssue(c, db)
+		if !ok {
+			return
+		}
 
-		result := db.First(&issue, "id = ?", id); 
-		if result.Error != nil {
-			c.JSON(404, gin.H{"error": "Issue not found"})
+		var fingerprints []string
+		err := db.Model(&models.IssueFingerprint{}).Where("issue_id = ?", issue.ID).Order("created_at").Pluck("fingerprint", &fingerprints).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
 			return
 		}
 
 		c.JSON(200, gin.H{
-			"message": "Issue resolved",
 			"issue": issue,
+			"fingerprints": fingerprints,
 		})
 	}
 }
 
-func ProcessEvent(conn *gorm.DB, e models.Event){
-	var issue models.Issue
-	fp := generateFingerprint(e.Message, e.StackTrace)
+// recordIssueEvents links events to their issue. Events without an ID
+// cannot be linked and are only counted.
+func recordIssueEvents(tx *gorm.DB, issueID, fingerprint string, events []models.Event) error {
+	var rows []models.IssueEvent
+	for _, e := range events {
+		if e.EventID == "" {
+			continue
+		}
 
-	err := conn.Where("project_id = ? AND fingerprint = ?", e.ProjectID, fp).First(&issue).Error
-	if err == nil{
-		err := conn.Model(&issue).Updates(map[string]interface{}{
-			"count":     gorm.Expr("count + ?", 1),
-			"last_seen": time.Now(),
+		timestamp := e.Timestamp
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+
+		rows = append(rows, models.IssueEvent{
+			EventID:     e.EventID,
+			IssueID:     issueID,
+			Fingerprint: fingerprint,
+			ProjectID:   e.ProjectID,
+			Timestamp:   timestamp,
+			UserKey:     userKey(e),
+			Tags:        eventTags(e),
+		})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// Limits on the tags recorded per event.
+const (
+	maxIssueTags = 50
+	maxTagLength = 200
+)
+
+// eventTags returns the tags an event counts towards its issue: its release,
+// environment and, within the limits, its other tags.
+func eventTags(e models.Event) map[string]string {
+	tags := map[string]string{}
+	add := func(key, value string) {
+		if key == "" || value == "" || len(key) > maxTagLength || len(value) > maxTagLength || len(tags) >= maxIssueTags {
+			return
+		}
+		tags[key] = value
+	}
+
+	add("release", e.Release)
+	add("environment", e.Environment)
+
+	keys := make([]string, 0, len(e.Tags))
+	for key := range e.Tags {
+		if key != "release" && key != "environment" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		add(key, e.Tags[key])
+	}
+
+	return tags
+}
+
+// recordIssueTags counts the releases, environments and tags of a batch of
+// an issue's events towards it, one row per distinct tag.
+func recordIssueTags(tx *gorm.DB, issueID string, events []models.Event) error {
+	now := time.Now()
+	counts := map[[2]string]int{}
+
+	for _, e := range events {
+		for key, value := range eventTags(e) {
+			counts[[2]string{key, value}]++
+		}
+	}
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	tags := make([]models.IssueTag, 0, len(counts))
+	for tag, n := range counts {
+		tags = append(tags, models.IssueTag{IssueID: issueID, Key: tag[0], Value: tag[1], Count: n, FirstSeen: now, LastSeen: now})
+	}
+	// A fixed order keeps concurrent batches from deadlocking.
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Key != tags[j].Key {
+			return tags[i].Key < tags[j].Key
+		}
+		return tags[i].Value < tags[j].Value
+	})
+
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "issue_id"}, {Name: "key"}, {Name: "value"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":     gorm.Expr("issue_tags.count + EXCLUDED.count"),
+			"last_seen": now,
+		}),
+	}).Create(&tags).Error
+}
+
+// Attempts at counting events before giving up, when the issue its
+// fingerprint maps to keeps being merged away.
+const maxUpsertAttempts = 3
+
+// mappedIssue returns the issue the first mapped fingerprint belongs to and
+// that fingerprint's index, or "" when none is mapped.
+func mappedIssue(tx *gorm.DB, projectID string, fps []string) (string, int, error) {
+	var mapping models.IssueFingerprint
+
+	for i, fp := range fps {
+		err := tx.Where("project_id = ? AND fingerprint = ?", projectID, fp).First(&mapping).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		return mapping.IssueID, i, nil
+	}
+
+	return "", 0, nil
+}
+
+// issueForFingerprints returns the issue the first mapped fingerprint
+// belongs to, or "" when none is mapped. A match through a fingerprint of
+// the previous grouping config also maps fps[0], so the issue is kept once
+// the transition is over.
+func issueForFingerprints(tx *gorm.DB, projectID string, fps []string) (string, error) {
+	issueID, i, err := mappedIssue(tx, projectID, fps)
+	if err != nil || issueID == "" {
+		return issueID, err
+	}
+
+	if i > 0 {
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.IssueFingerprint{
+			ProjectID:   projectID,
+			Fingerprint: fps[0],
+			IssueID:     issueID,
 		}).Error
+		if err != nil {
+			return "", err
+		}
+	}
+	return issueID, nil
+}
+
+// upsertIssue adds n events to the issue of their fingerprints, or creates
+// that issue from e, in a single statement returning the row as it will be
+// committed. The row stays locked until the transaction ends, so consumers
+// racing on the same fingerprint neither violate idx_project_fp nor publish
+// stale counts. It reports whether the issue was created.
+func upsertIssue(tx *gorm.DB, e models.Event, fps []string, settings models.ProjectSettings, n int) (models.Issue, bool, error) {
+	now := time.Now()
+
+	for attempt := 0; attempt < maxUpsertAttempts; attempt++ {
+		var issue models.Issue
 
+		issueID, err := issueForFingerprints(tx, e.ProjectID, fps)
 		if err != nil {
-			log.Printf("Failed to update issue %s: %v", issue.ID, err)
+			return issue, false, err
 		}
 
-		conn.First(&issue, "id = ?", issue.ID)
+		if issueID != "" {
+			updates := map[string]interface{}{
+				"count":     gorm.Expr("count + ?", n),
+				"last_seen": now,
+			}
+			if e.Release != "" {
+				updates["last_release"] = e.Release
+			}
+
+			result := tx.Model(&issue).Clauses(clause.Returning{}).
+				Where("id = ? AND merged_into_id IS NULL", issueID).
+				Updates(updates)
+			if result.Error != nil {
+				return issue, false, result.Error
+			}
+			if result.RowsAffected == 1 {
+				return issue, false, nil
+			}
+
+			// Merged since the lookup: the mapping now names the primary.
+			continue
+		}
 
-		updateEvent := IssueUpdateEvent{
-			IssueID:   issue.ID,
-			ProjectID: issue.ProjectID,
-			Count:     issue.Count,
-			Level:     issue.Level,
-			Status:    issue.Status,
-			UpdatedAt: time.Now(),
+		issue = models.Issue{
+			ID:             uuid.New().String(),
+			ProjectID:      e.ProjectID,
+			Fingerprint:    fps[0],
+			GroupingConfig: settings.GroupingConfig,
+			Title:          e.Message,
+			Level:          e.Level,
+			Count:          n,
+			FirstSeen:      now,
+			LastSeen:       now,
+			Status:         StatusOpen,
+			LastRelease:    e.Release,
+		}
+		newID := issue.ID
+
+		result := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "project_id"}, {Name: "fingerprint"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"count":        gorm.Expr("issues.count + ?", n),
+				"last_seen":    now,
+				"last_release": gorm.Expr("COALESCE(NULLIF(EXCLUDED.last_release, ''), issues.last_release)"),
+				"updated_at":   now,
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "issues.merged_into_id IS NULL"}}},
+		}, clause.Returning{}).Create(&issue)
+		if result.Error != nil {
+			return issue, false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// The fingerprint belongs to an issue merged elsewhere.
+			continue
 		}
 
-		publisher.PublishEvent(issueUpdateWriter, issue.ProjectID, updateEvent)
+		// Another consumer may have created the issue and its mapping
+		// first; the mapping is then already there.
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.IssueFingerprint{
+			ProjectID:   e.ProjectID,
+			Fingerprint: fps[0],
+			IssueID:     issue.ID,
+		}).Error
+		if err != nil {
+			return issue, false, err
+		}
 
-		return
+		return issue, issue.ID == newID, nil
+	}
+
+	return models.Issue{}, false, fmt.Errorf("issue for fingerprint %s kept changing", fps[0])
+}
+
+// markProcessed records the IDs of a batch of events and returns the events
+// not processed before, in order. Events without an ID are always processed.
+func markProcessed(tx *gorm.DB, events []models.Event) ([]models.Event, error) {
+	var ids, projectIDs []string
+	seen := map[[2]string]bool{}
+	for _, e := range events {
+		key := [2]string{e.ProjectID, e.EventID}
+		if e.EventID != "" && !seen[key] {
+			seen[key] = true
+			ids = append(ids, e.EventID)
+			projectIDs = append(projectIDs, e.ProjectID)
+		}
+	}
+
+	var inserted []models.ProcessedEvent
+	if len(ids) > 0 {
+		err := tx.Raw(`INSERT INTO processed_events (project_id, event_id, processed_at)
+			SELECT project_id, id, ?::timestamptz FROM unnest(?::uuid[], ?::text[]) AS t(id, project_id)
+			ON CONFLICT DO NOTHING
+			RETURNING project_id, event_id`, time.Now(), pq.StringArray(ids), pq.StringArray(projectIDs)).Scan(&inserted).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fresh := map[[2]string]bool{}
+	for _, p := range inserted {
+		fresh[[2]string{p.ProjectID, p.EventID}] = true
+	}
+
+	var out []models.Event
+	for _, e := range events {
+		key := [2]string{e.ProjectID, e.EventID}
+		switch {
+		case e.EventID == "":
+			out = append(out, e)
+		case fresh[key]:
+			out = append(out, e)
+			// Later copies in the same batch are duplicates.
+			delete(fresh, key)
+		default:
+			log.Println("Skipping duplicate event:", e.EventID)
+		}
+	}
+	return out, nil
+}
+
+// eventGroup is the events of a batch sharing a project and fingerprint.
+type eventGroup struct {
+	settings models.ProjectSettings
+	rules    grouping.Rules
+	fps      []string
+	events   []models.Event
+}
+
+// summary is the event an issue is created from: the group's first event,
+// carrying the latest release seen.
+func (g *eventGroup) summary() models.Event {
+	e := g.events[0]
+	for _, other := range g.events {
+		if other.Release != "" {
+			e.Release = other.Release
+		}
 	}
+	return e
+}
 
-	newIssue := models.Issue{
-		ID:          uuid.New().String(),
-		ProjectID:   e.ProjectID,
-		Fingerprint: fp,
-		Title:       e.Message,
-		Level:       e.Level,
-		Count:       1,
-		FirstSeen:   time.Now(),
-		LastSeen:    time.Now(),
-		Status:      "open",
+// groupEvents fingerprints a batch of events and groups them by project and
+// fingerprint, in a fixed order so concurrent batches lock issues alike.
+func groupEvents(tx *gorm.DB, events []models.Event) ([]*eventGroup, error) {
+	type project struct {
+		settings models.ProjectSettings
+		rules    grouping.Rules
 	}
+	projects := map[string]*project{}
+	releases := map[[2]string]bool{}
+	groups := map[[2]string]*eventGroup{}
+
+	for _, e := range events {
+		p, ok := projects[e.ProjectID]
+		if !ok {
+			settings, err := loadProjectSettings(tx, e.ProjectID)
+			if err != nil {
+				return nil, err
+			}
+			rules, err := loadGroupingRules(tx, e.ProjectID)
+			if err != nil {
+				return nil, err
+			}
+			p = &project{settings: settings, rules: rules}
+			projects[e.ProjectID] = p
+		}
 
-	err = conn.Create(&newIssue).Error
-	if err != nil{
-		log.Println("Failed to create issue:", err)
+		if !releases[[2]string{e.ProjectID, e.Release}] {
+			releases[[2]string{e.ProjectID, e.Release}] = true
+			err := recordRelease(tx, e.ProjectID, e.Release)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		fps := fingerprints(p.settings, p.rules, e)
+		key := [2]string{e.ProjectID, fps[0]}
+		g, ok := groups[key]
+		if !ok {
+			g = &eventGroup{settings: p.settings, rules: p.rules, fps: fps}
+			groups[key] = g
+		}
+		g.events = append(g.events, e)
+	}
+
+	keys := make([][2]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	out := make([]*eventGroup, len(keys))
+	for i, key := range keys {
+		out[i] = groups[key]
+	}
+	return out, nil
+}
+
+// processGroup counts a group's events towards their issue. It returns the
+// issue as committed, plus the state change and assignment to publish.
+func processGroup(tx *gorm.DB, g *eventGroup) (models.Issue, *IssueStateChangeEvent, *IssueAssignmentEvent, error) {
+	issue, created, err := upsertIssue(tx, g.summary(), g.fps, g.settings, len(g.events))
+	if err != nil {
+		return issue, nil, nil, err
+	}
+
+	err = recordIssueUsers(tx, issue.ID, g.events)
+	if err != nil {
+		return issue, nil, nil, err
+	}
+
+	err = recordIssueTags(tx, issue.ID, g.events)
+	if err != nil {
+		return issue, nil, nil, err
+	}
+
+	err = recordIssueEvents(tx, issue.ID, g.fps[0], g.events)
+	if err != nil {
+		return issue, nil, nil, err
+	}
+
+	if created {
+		first := g.events[0]
+		err = recordActivity(tx, &issue, ActivityFirstSeen, "", map[string]interface{}{
+			"event_id": first.EventID,
+			"release":  first.Release,
+		})
+		if err != nil {
+			return issue, nil, nil, err
+		}
+
+		assignment, err := autoAssign(tx, &issue, first, g.rules)
+		return issue, nil, assignment, err
+	}
+
+	// Any event of the batch can regress or unignore the issue; releases
+	// decide, so each is checked once.
+	checked := map[string]bool{}
+	for _, e := range g.events {
+		if checked[e.Release] {
+			continue
+		}
+		checked[e.Release] = true
+
+		change, err := applyEventTransition(tx, &issue, e)
+		if err != nil || change != nil {
+			return issue, change, nil, err
+		}
+	}
+
+	return issue, nil, nil, nil
+}
+
+// storedEventLink is the issue a group of events was counted towards.
+type storedEventLink struct {
+	issueID     string
+	fingerprint string
+	events      []models.Event
+}
+
+// linkStoredEvents records on Kafka Service's stored events the issue and
+// fingerprint they were grouped into. It runs after the batch commits:
+// Kafka Service links events it stores later from issue_events, so between
+// the two every stored event ends up linked. Failures are only logged.
+func linkStoredEvents(conn *gorm.DB, links []storedEventLink) {
+	if len(links) == 0 || !conn.Migrator().HasTable(storedEvents) {
 		return
 	}
 
-	updateEvent := IssueUpdateEvent{
-		IssueID:   newIssue.ID,
-		ProjectID: newIssue.ProjectID,
-		Count:     newIssue.Count,
-		Level:     newIssue.Level,
-		Status:    newIssue.Status,
-		UpdatedAt: time.Now(),
+	for _, link := range links {
+		var ids []string
+		for _, e := range link.events {
+			if e.EventID != "" {
+				ids = append(ids, e.EventID)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		// A group's events share a project.
+		projectID := link.events[0].ProjectID
+		err := conn.Table(storedEvents).Where("project_id = ? AND id IN ?", projectID, ids).Updates(map[string]interface{}{
+			"issue_id":    link.issueID,
+			"fingerprint": link.fingerprint,
+		}).Error
+		if err != nil {
+			log.Printf("Failed to link stored events to issue %s: %v", link.issueID, err)
+		}
+	}
+}
+
+// ProcessEvents counts a batch of events towards their issues in one
+// transaction. Events are aggregated per project and fingerprint, so each
+// issue is upserted once per batch however many of its events arrived, and
+// one update per issue is published after the commit. On error nothing is
+// written, and the batch can be retried.
+func ProcessEvents(conn *gorm.DB, events []models.Event) error {
+	var issues []models.Issue
+	var stateChanges []*IssueStateChangeEvent
+	var assignments []*IssueAssignmentEvent
+	var links []storedEventLink
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		fresh, err := markProcessed(tx, events)
+		if err != nil {
+			return err
+		}
+
+		groups, err := groupEvents(tx, fresh)
+		if err != nil {
+			return err
+		}
+
+		// Fingerprints merged into the same issue share one update.
+		latest := map[string]int{}
+		for _, g := range groups {
+			issue, stateChange, assignment, err := processGroup(tx, g)
+			if err != nil {
+				return err
+			}
+			links = append(links, storedEventLink{issueID: issue.ID, fingerprint: g.fps[0], events: g.events})
+
+			if i, ok := latest[issue.ID]; ok {
+				issues[i] = issue
+			} else {
+				latest[issue.ID] = len(issues)
+				issues = append(issues, issue)
+			}
+			if stateChange != nil {
+				stateChanges = append(stateChanges, stateChange)
+			}
+			if assignment != nil {
+				assignments = append(assignments, assignment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	linkStoredEvents(conn, links)
+
+	for _, issue := range issues {
+		publishIssueUpdate(issue)
+	}
+
+	for _, stateChange := range stateChanges {
+		publisher.PublishEvent(issueStateWriter, stateChange.ProjectID, stateChange)
+	}
+
+	for _, assignment := range assignments {
+		publisher.PublishEvent(issueAssignmentWriter, assignment.ProjectID, assignment)
 	}
 
-	publisher.PublishEvent(issueUpdateWriter, newIssue.ProjectID, updateEvent)
+	return nil
 }
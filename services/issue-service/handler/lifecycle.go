@@ -0,0 +1,392 @@
+package handler
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/k1ngalph0x/beacon/services/issue-service/models"
+	publisher "github.com/k1ngalph0x/beacon/services/issue-service/utils"
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Issue statuses. Open and regressed issues are unresolved; regressed is
+// only ever set by a new event.
+const (
+	StatusOpen                  = "open"
+	StatusResolved              = "resolved"
+	StatusResolvedInNextRelease = "resolved_in_next_release"
+	StatusIgnored               = "ignored"
+	StatusIgnoredUntil          = "ignored_until"
+	StatusRegressed             = "regressed"
+)
+
+// Transitions published on the issue-state-changes topic.
+const (
+	TransitionResolved  = "resolved"
+	TransitionIgnored   = "ignored"
+	TransitionReopened  = "reopened"
+	TransitionRegressed = "regressed"
+	TransitionUnignored = "unignored"
+	// Published when an issue is deleted, with To set to "deleted".
+	TransitionDeleted = "deleted"
+)
+
+type IssueStateChangeEvent struct {
+	IssueID    string    `json:"issue_id"`
+	ProjectID  string    `json:"project_id"`
+	Transition string    `json:"transition"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Release    string    `json:"release,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+var issueStateWriter = &kafka.Writer{
+	Addr:     kafka.TCP("localhost:9092"),
+	Topic:    "issue-state-changes",
+	Balancer: &kafka.LeastBytes{},
+}
+
+type UpdateStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	// Release that fixed a resolved issue.
+	InRelease string `json:"in_release"`
+	// Conditions for ignored_until; at least one is required.
+	IgnoreUntil     *time.Time `json:"ignore_until"`
+	IgnoreCount     *int       `json:"ignore_count"`
+	IgnoreUserCount *int       `json:"ignore_user_count"`
+}
+
+// StatusChange describes a status update applied with setStatus.
+type StatusChange struct {
+	Status string
+	// Release that fixed a resolved issue; for resolved_in_next_release it
+	// defaults to the project's latest release.
+	Release         string
+	IgnoreUntil     *time.Time
+	IgnoreCount     *int
+	IgnoreUserCount *int
+	// User making the change; empty for automatic transitions.
+	By string
+}
+
+// setStatus moves an issue to a new status, clearing the fields of the
+// previous one. When the status changes it logs the transition and returns
+// it for publishing.
+func setStatus(tx *gorm.DB, issue *models.Issue, change StatusChange) (*IssueStateChangeEvent, error) {
+	now := time.Now()
+	from := issue.Status
+
+	release := change.Release
+	switch change.Status {
+	case StatusResolved:
+		err := recordRelease(tx, issue.ProjectID, release)
+		if err != nil {
+			return nil, err
+		}
+	case StatusResolvedInNextRelease:
+		if release == "" {
+			latest, err := latestRelease(tx, issue.ProjectID)
+			if err != nil {
+				return nil, err
+			}
+			release = latest
+		}
+	}
+
+	issue.Status = change.Status
+	issue.ResolvedAt = nil
+	issue.ResolvedInRelease = ""
+	issue.IgnoredAt = nil
+	issue.IgnoreUntil = nil
+	issue.IgnoreCount = nil
+	issue.IgnoreUserCount = nil
+	issue.IgnoreBaseCount = 0
+
+	var transition string
+	switch change.Status {
+	case StatusResolved, StatusResolvedInNextRelease:
+		transition = TransitionResolved
+		issue.ResolvedAt = &now
+		issue.ResolvedInRelease = release
+	case StatusIgnored, StatusIgnoredUntil:
+		transition = TransitionIgnored
+		issue.IgnoredAt = &now
+		if change.Status == StatusIgnoredUntil {
+			issue.IgnoreUntil = change.IgnoreUntil
+			issue.IgnoreCount = change.IgnoreCount
+			issue.IgnoreUserCount = change.IgnoreUserCount
+			issue.IgnoreBaseCount = issue.Count
+		}
+	case StatusRegressed:
+		transition = TransitionRegressed
+	case StatusOpen:
+		transition = TransitionReopened
+		if from == StatusIgnored || from == StatusIgnoredUntil {
+			transition = TransitionUnignored
+		}
+	}
+
+	err := tx.Model(issue).Select("status", "resolved_at", "resolved_in_release", "ignored_at", "ignore_until",
+		"ignore_count", "ignore_user_count", "ignore_base_count").Updates(issue).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if from == change.Status {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{"from": from, "to": change.Status}
+	if issue.ResolvedInRelease != "" {
+		data["release"] = issue.ResolvedInRelease
+	}
+	err = recordActivity(tx, issue, transition, change.By, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// A resolution carries the release it is resolved in, other transitions
+	// the release the issue was last seen in.
+	eventRelease := issue.LastRelease
+	if transition == TransitionResolved {
+		eventRelease = issue.ResolvedInRelease
+	}
+
+	return &IssueStateChangeEvent{
+		IssueID:    issue.ID,
+		ProjectID:  issue.ProjectID,
+		Transition: transition,
+		From:       from,
+		To:         change.Status,
+		Release:    eventRelease,
+		ChangedAt:  now,
+	}, nil
+}
+
+// userKey identifies the user affected by an event, or returns "" when the
+// event says nothing about its user.
+func userKey(e models.Event) string {
+	if u := e.User; u != nil {
+		switch {
+		case u.ID != "":
+			return "id:" + u.ID
+		case u.Email != "":
+			return "email:" + u.Email
+		case u.Username != "":
+			return "username:" + u.Username
+		case u.IPAddress != "":
+			return "ip:" + u.IPAddress
+		}
+	}
+	if e.Client != nil && e.Client.IP != "" {
+		return "ip:" + e.Client.IP
+	}
+	return ""
+}
+
+// recordIssueUsers records the users affected by a batch of an issue's
+// events and adds the new ones to its user_count.
+func recordIssueUsers(tx *gorm.DB, issueID string, events []models.Event) error {
+	seen := map[string]bool{}
+	var keys []string
+	for _, e := range events {
+		key := userKey(e)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+
+	now := time.Now()
+	users := make([]models.IssueUser, len(keys))
+	for i, key := range keys {
+		users[i] = models.IssueUser{IssueID: issueID, UserKey: key, FirstSeen: now, LastSeen: now}
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&users)
+	if result.Error != nil {
+		return result.Error
+	}
+	added := result.RowsAffected
+
+	if added < int64(len(keys)) {
+		err := tx.Model(&models.IssueUser{}).Where("issue_id = ? AND user_key IN ?", issueID, keys).
+			Update("last_seen", now).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if added == 0 {
+		return nil
+	}
+	return tx.Model(&models.Issue{}).Where("id = ?", issueID).
+		UpdateColumn("user_count", gorm.Expr("user_count + ?", added)).Error
+}
+
+// ignoreExpired reports whether any condition of an ignored_until issue has
+// been reached.
+func ignoreExpired(tx *gorm.DB, issue *models.Issue) (bool, error) {
+	if issue.IgnoreUntil != nil && time.Now().After(*issue.IgnoreUntil) {
+		return true, nil
+	}
+
+	if issue.IgnoreCount != nil && issue.Count-issue.IgnoreBaseCount >= *issue.IgnoreCount {
+		return true, nil
+	}
+
+	if issue.IgnoreUserCount != nil && issue.IgnoredAt != nil {
+		var users int64
+		err := tx.Model(&models.IssueUser{}).
+			Where("issue_id = ? AND first_seen >= ?", issue.ID, *issue.IgnoredAt).
+			Count(&users).Error
+		if err != nil {
+			return false, err
+		}
+		if users >= int64(*issue.IgnoreUserCount) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// applyEventTransition moves an issue that just received an event: resolved
+// issues regress unless the event comes from a release older than the fix,
+// and ignored_until issues reopen once a condition is met.
+func applyEventTransition(tx *gorm.DB, issue *models.Issue, e models.Event) (*IssueStateChangeEvent, error) {
+	switch issue.Status {
+	case StatusResolved, StatusResolvedInNextRelease:
+		regressed, err := regressedByRelease(tx, issue, e.Release)
+		if err != nil || !regressed {
+			return nil, err
+		}
+		return setStatus(tx, issue, StatusChange{Status: StatusRegressed})
+
+	case StatusIgnoredUntil:
+		expired, err := ignoreExpired(tx, issue)
+		if err != nil || !expired {
+			return nil, err
+		}
+		return setStatus(tx, issue, StatusChange{Status: StatusOpen})
+	}
+
+	return nil, nil
+}
+
+func validStatusRequest(req UpdateStatusRequest) error {
+	if req.InRelease != "" && req.Status != StatusResolved {
+		return errors.New("in_release only applies to resolved")
+	}
+
+	switch req.Status {
+	case StatusOpen, StatusResolved, StatusResolvedInNextRelease, StatusIgnored:
+		return nil
+	case StatusIgnoredUntil:
+		if req.IgnoreUntil == nil && req.IgnoreCount == nil && req.IgnoreUserCount == nil {
+			return errors.New("ignored_until needs ignore_until, ignore_count or ignore_user_count")
+		}
+		if (req.IgnoreCount != nil && *req.IgnoreCount <= 0) || (req.IgnoreUserCount != nil && *req.IgnoreUserCount <= 0) {
+			return errors.New("ignore counts must be positive")
+		}
+		return nil
+	}
+	return errors.New("status must be one of open, resolved, resolved_in_next_release, ignored, ignored_until")
+}
+
+// UpdateIssueStatus resolves, ignores or reopens an issue.
+func UpdateIssueStatus(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UpdateStatusRequest
+		var issue models.Issue
+
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.JSON(401, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		err = validStatusRequest(req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		err = db.First(&issue, "id = ?", c.Param("id")).Error
+		if err != nil {
+			c.JSON(404, gin.H{"error": "Issue not found"})
+			return
+		}
+
+		if !verifyProjectOwnership(db, userID, issue.ProjectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		var change *IssueStateChangeEvent
+		err = db.Transaction(func(tx *gorm.DB) error {
+			change, err = setStatus(tx, &issue, StatusChange{
+				Status:          req.Status,
+				Release:         req.InRelease,
+				IgnoreUntil:     req.IgnoreUntil,
+				IgnoreCount:     req.IgnoreCount,
+				IgnoreUserCount: req.IgnoreUserCount,
+				By:              userID,
+			})
+			return err
+		})
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to update issue"})
+			return
+		}
+
+		if change != nil {
+			publisher.PublishEvent(issueStateWriter, issue.ProjectID, change)
+		}
+
+		c.JSON(200, gin.H{"issue": issue})
+	}
+}
+
+// ReopenExpiredIgnores reopens ignored_until issues whose ignore_until has
+// passed, so they do not wait for their next event.
+func ReopenExpiredIgnores(db *gorm.DB) error {
+	var issues []models.Issue
+
+	err := db.Where("status = ? AND ignore_until < ?", StatusIgnoredUntil, time.Now()).Find(&issues).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range issues {
+		var change *IssueStateChangeEvent
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			change, err = setStatus(tx, &issues[i], StatusChange{Status: StatusOpen})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if change != nil {
+			publisher.PublishEvent(issueStateWriter, issues[i].ProjectID, change)
+		}
+	}
+
+	return nil
+}
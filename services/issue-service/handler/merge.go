@@ -0,0 +1,422 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/k1ngalph0x/beacon/services/issue-service/models"
+	publisher "github.com/k1ngalph0x/beacon/services/issue-service/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MergeIssuesRequest struct {
+	IssueIDs []string `json:"issue_ids" binding:"required"`
+	// Issue the others are merged into. Defaults to the first of IssueIDs.
+	PrimaryID string `json:"primary_id"`
+}
+
+type UnmergeIssueRequest struct {
+	Fingerprint string `json:"fingerprint" binding:"required"`
+}
+
+var errMergeConflict = errors.New("issue changed while merging")
+
+func publishIssueUpdate(issue models.Issue) {
+	publisher.PublishEvent(issueUpdateWriter, issue.ProjectID, IssueUpdateEvent{
+		IssueID:   issue.ID,
+		ProjectID: issue.ProjectID,
+		Count:     issue.Count,
+		Level:     issue.Level,
+		Status:    issue.Status,
+		Assignee:  issue.Assignee,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// MergeIssues folds issues of one project into a primary issue. Their
+// fingerprints, events, users and tags move to the primary, which takes over
+// their counts; the merged issues are kept with MergedIntoID set.
+func MergeIssues(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MergeIssuesRequest
+		var issues []models.Issue
+
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.JSON(401, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		primaryID := req.PrimaryID
+		if primaryID == "" && len(req.IssueIDs) > 0 {
+			primaryID = req.IssueIDs[0]
+		}
+
+		ids := map[string]bool{primaryID: true}
+		for _, id := range req.IssueIDs {
+			ids[id] = true
+		}
+		if len(ids) < 2 {
+			c.JSON(400, gin.H{"error": "At least two issues are required"})
+			return
+		}
+
+		var all []string
+		for id := range ids {
+			all = append(all, id)
+		}
+
+		err = db.Where("id IN ?", all).Find(&issues).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+		if len(issues) != len(all) {
+			c.JSON(404, gin.H{"error": "Issue not found"})
+			return
+		}
+
+		var primary models.Issue
+		var others []string
+		for _, issue := range issues {
+			if issue.ProjectID != issues[0].ProjectID {
+				c.JSON(400, gin.H{"error": "Issues belong to different projects"})
+				return
+			}
+			if issue.MergedIntoID != nil {
+				c.JSON(409, gin.H{"error": "Issue is already merged", "issue_id": issue.ID})
+				return
+			}
+
+			if issue.ID == primaryID {
+				primary = issue
+			} else {
+				others = append(others, issue.ID)
+			}
+		}
+
+		if !verifyProjectOwnership(db, userID, primary.ProjectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// Lock the primary so it cannot be merged elsewhere meanwhile.
+			var locked models.Issue
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", primary.ID).Error
+			if err != nil {
+				return err
+			}
+			if locked.MergedIntoID != nil {
+				return errMergeConflict
+			}
+
+			// Issues previously merged into the others follow them.
+			err = tx.Model(&models.Issue{}).Where("merged_into_id IN ?", others).
+				Update("merged_into_id", primary.ID).Error
+			if err != nil {
+				return err
+			}
+
+			result := tx.Model(&models.Issue{}).Where("id IN ? AND merged_into_id IS NULL", others).
+				Update("merged_into_id", primary.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != int64(len(others)) {
+				return errMergeConflict
+			}
+
+			err = tx.Model(&models.IssueFingerprint{}).Where("issue_id IN ?", others).
+				Update("issue_id", primary.ID).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&models.IssueEvent{}).Where("issue_id IN ?", others).
+				Update("issue_id", primary.ID).Error
+			if err != nil {
+				return err
+			}
+
+			err = moveStoredEvents(tx, &primary.ID, "issue_id IN ?", others)
+			if err != nil {
+				return err
+			}
+
+			err = tx.Exec(`INSERT INTO issue_users (issue_id, user_key, first_seen, last_seen)
+				SELECT ?, user_key, MIN(first_seen), MAX(last_seen) FROM issue_users
+				WHERE issue_id IN ? GROUP BY user_key
+				ON CONFLICT (issue_id, user_key) DO UPDATE SET
+				first_seen = LEAST(issue_users.first_seen, EXCLUDED.first_seen),
+				last_seen = GREATEST(issue_users.last_seen, EXCLUDED.last_seen)`, primary.ID, others).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Where("issue_id IN ?", others).Delete(&models.IssueUser{}).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Exec(`INSERT INTO issue_tags (issue_id, key, value, count, first_seen, last_seen)
+				SELECT ?, key, value, SUM(count), MIN(first_seen), MAX(last_seen) FROM issue_tags
+				WHERE issue_id IN ? GROUP BY key, value
+				ON CONFLICT (issue_id, key, value) DO UPDATE SET
+				count = issue_tags.count + EXCLUDED.count,
+				first_seen = LEAST(issue_tags.first_seen, EXCLUDED.first_seen),
+				last_seen = GREATEST(issue_tags.last_seen, EXCLUDED.last_seen)`, primary.ID, others).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Where("issue_id IN ?", others).Delete(&models.IssueTag{}).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Exec(`UPDATE issues SET
+				count = count + (SELECT COALESCE(SUM(count), 0) FROM issues WHERE id IN ?),
+				first_seen = LEAST(first_seen, (SELECT MIN(first_seen) FROM issues WHERE id IN ?)),
+				last_seen = GREATEST(last_seen, (SELECT MAX(last_seen) FROM issues WHERE id IN ?)),
+				user_count = (SELECT COUNT(*) FROM issue_users WHERE issue_id = ?),
+				updated_at = ?
+				WHERE id = ?`, others, others, others, primary.ID, time.Now(), primary.ID).Error
+			if err != nil {
+				return err
+			}
+
+			err = recordActivity(tx, &primary, ActivityMerged, userID, map[string]interface{}{"issue_ids": others})
+			if err != nil {
+				return err
+			}
+			for _, issue := range issues {
+				if issue.ID == primary.ID {
+					continue
+				}
+				err = recordActivity(tx, &issue, ActivityMergedInto, userID, map[string]interface{}{"issue_id": primary.ID})
+				if err != nil {
+					return err
+				}
+			}
+
+			return tx.First(&primary, "id = ?", primary.ID).Error
+		})
+
+		if errors.Is(err, errMergeConflict) {
+			c.JSON(409, gin.H{"error": "Issue is already merged"})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to merge issues"})
+			return
+		}
+
+		publishIssueUpdate(primary)
+
+		c.JSON(200, gin.H{"issue": primary, "merged": others})
+	}
+}
+
+// UnmergeIssue splits a fingerprint back out of an issue. The issue that
+// originally owned the fingerprint is restored, or a new one is created,
+// and counts are moved according to the stored events. The users and tags
+// of both issues are rebuilt from their events.
+func UnmergeIssue(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UnmergeIssueRequest
+		var issue models.Issue
+		var split models.Issue
+
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.JSON(401, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		err = db.First(&issue, "id = ?", c.Param("id")).Error
+		if err != nil {
+			c.JSON(404, gin.H{"error": "Issue not found"})
+			return
+		}
+
+		if !verifyProjectOwnership(db, userID, issue.ProjectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if req.Fingerprint == issue.Fingerprint {
+			c.JSON(400, gin.H{"error": "Cannot unmerge the issue's own fingerprint"})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var mapping models.IssueFingerprint
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("project_id = ? AND fingerprint = ? AND issue_id = ?", issue.ProjectID, req.Fingerprint, issue.ID).
+				First(&mapping).Error
+			if err != nil {
+				return err
+			}
+
+			var stats struct {
+				Count     int
+				FirstSeen *time.Time
+				LastSeen  *time.Time
+			}
+			err = tx.Model(&models.IssueEvent{}).
+				Select("COUNT(*) AS count, MIN(timestamp) AS first_seen, MAX(timestamp) AS last_seen").
+				Where("issue_id = ? AND fingerprint = ?", issue.ID, req.Fingerprint).
+				Scan(&stats).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Where("project_id = ? AND fingerprint = ? AND merged_into_id = ?", issue.ProjectID, req.Fingerprint, issue.ID).
+				First(&split).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				now := time.Now()
+				split = models.Issue{
+					ID:             uuid.New().String(),
+					ProjectID:      issue.ProjectID,
+					Fingerprint:    req.Fingerprint,
+					GroupingConfig: issue.GroupingConfig,
+					Title:          issue.Title,
+					Level:          issue.Level,
+					FirstSeen:      now,
+					LastSeen:       now,
+					Status:         "open",
+				}
+			case err != nil:
+				return err
+			}
+
+			split.MergedIntoID = nil
+			split.Count = stats.Count
+			if stats.FirstSeen != nil {
+				split.FirstSeen = *stats.FirstSeen
+				split.LastSeen = *stats.LastSeen
+			}
+
+			err = tx.Save(&split).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&models.IssueFingerprint{}).
+				Where("project_id = ? AND fingerprint = ?", issue.ProjectID, req.Fingerprint).
+				Update("issue_id", split.ID).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&models.IssueEvent{}).
+				Where("issue_id = ? AND fingerprint = ?", issue.ID, req.Fingerprint).
+				Update("issue_id", split.ID).Error
+			if err != nil {
+				return err
+			}
+
+			err = moveStoredEvents(tx, &split.ID, "issue_id = ? AND fingerprint = ?", issue.ID, req.Fingerprint)
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&issue).Updates(map[string]interface{}{
+				"count": gorm.Expr("GREATEST(count - ?, 0)", stats.Count),
+			}).Error
+			if err != nil {
+				return err
+			}
+
+			err = rebuildUsersAndTags(tx, []string{issue.ID, split.ID})
+			if err != nil {
+				return err
+			}
+
+			err = recordActivity(tx, &issue, ActivityUnmerged, userID, map[string]interface{}{
+				"fingerprint": req.Fingerprint,
+				"issue_id":    split.ID,
+			})
+			if err != nil {
+				return err
+			}
+			err = recordActivity(tx, &split, ActivityUnmerged, userID, map[string]interface{}{
+				"fingerprint": req.Fingerprint,
+				"issue_id":    issue.ID,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = tx.First(&split, "id = ?", split.ID).Error
+			if err != nil {
+				return err
+			}
+			return tx.First(&issue, "id = ?", issue.ID).Error
+		})
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "Fingerprint is not merged into this issue"})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to unmerge issue"})
+			return
+		}
+
+		publishIssueUpdate(issue)
+		publishIssueUpdate(split)
+
+		c.JSON(200, gin.H{"issue": issue, "unmerged": split})
+	}
+}
+
+// rebuildUsersAndTags recounts the users and tags of issues from the
+// issue_events linked to them.
+func rebuildUsersAndTags(tx *gorm.DB, issueIDs []string) error {
+	err := tx.Where("issue_id IN ?", issueIDs).Delete(&models.IssueUser{}).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec(`INSERT INTO issue_users (issue_id, user_key, first_seen, last_seen)
+		SELECT issue_id, user_key, MIN(timestamp), MAX(timestamp) FROM issue_events
+		WHERE issue_id IN ? AND user_key <> '' GROUP BY issue_id, user_key`, issueIDs).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Where("issue_id IN ?", issueIDs).Delete(&models.IssueTag{}).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec(`INSERT INTO issue_tags (issue_id, key, value, count, first_seen, last_seen)
+		SELECT issue_id, tag.key, tag.value, COUNT(*), MIN(timestamp), MAX(timestamp)
+		FROM issue_events, jsonb_each_text(issue_events.tags) AS tag
+		WHERE issue_id IN ? GROUP BY issue_id, tag.key, tag.value`, issueIDs).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Exec(`UPDATE issues SET user_count =
+		(SELECT COUNT(*) FROM issue_users WHERE issue_users.issue_id = issues.id)
+		WHERE id IN ?`, issueIDs).Error
+}
@@ -0,0 +1,305 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/k1ngalph0x/beacon/services/issue-service/grouping"
+	"github.com/k1ngalph0x/beacon/services/issue-service/models"
+	"github.com/k1ngalph0x/beacon/services/issue-service/ownership"
+	publisher "github.com/k1ngalph0x/beacon/services/issue-service/utils"
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+)
+
+type IssueAssignmentEvent struct {
+	IssueID   string `json:"issue_id"`
+	ProjectID string `json:"project_id"`
+	// Empty when the issue was unassigned.
+	Assignee         string `json:"assignee,omitempty"`
+	PreviousAssignee string `json:"previous_assignee,omitempty"`
+	// "user" for manual changes, "ownership_rule" for auto-assignment.
+	Source     string    `json:"source"`
+	RuleID     string    `json:"rule_id,omitempty"`
+	AssignedBy string    `json:"assigned_by,omitempty"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+var issueAssignmentWriter = &kafka.Writer{
+	Addr:     kafka.TCP("localhost:9092"),
+	Topic:    "issue-assignments",
+	Balancer: &kafka.LeastBytes{},
+}
+
+type AssignIssueRequest struct {
+	// "user:<id>", "team:<name>" or "me"; null unassigns.
+	Assignee *string `json:"assignee"`
+}
+
+type CreateOwnershipRuleRequest struct {
+	Type    string   `json:"type" binding:"required"`
+	Pattern string   `json:"pattern" binding:"required"`
+	Owners  []string `json:"owners" binding:"required"`
+	// Defaults to after the existing rules.
+	Position *int `json:"position"`
+}
+
+func loadOwnershipRules(db *gorm.DB, projectID string) ([]ownership.Rule, error) {
+	var rows []models.OwnershipRule
+
+	err := db.Where("project_id = ?", projectID).Order("position, created_at").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]ownership.Rule, len(rows))
+	for i, r := range rows {
+		rules[i] = ownership.NewRule(r.ID, r.Type, r.Pattern, r.Owners)
+	}
+	return rules, nil
+}
+
+// autoAssign assigns a new issue from the project's ownership rules. It
+// returns the assignment to publish, if any rule matched.
+func autoAssign(tx *gorm.DB, issue *models.Issue, e models.Event, rules grouping.Rules) (*IssueAssignmentEvent, error) {
+	ownershipRules, err := loadOwnershipRules(tx, issue.ProjectID)
+	if err != nil || len(ownershipRules) == 0 {
+		return nil, err
+	}
+
+	frames := grouping.Input{StackTrace: e.StackTrace, Rules: rules}.Frames()
+	owners, rule, ok := ownership.Owners(ownershipRules, ownership.Event{Frames: frames, Tags: e.Tags})
+	if !ok {
+		return nil, nil
+	}
+
+	issue.Assignee = &owners[0]
+	err = tx.Model(issue).Update("assignee", owners[0]).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordActivity(tx, issue, ActivityAssigned, "", map[string]interface{}{
+		"assignee": owners[0],
+		"rule_id":  rule.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssueAssignmentEvent{
+		IssueID:    issue.ID,
+		ProjectID:  issue.ProjectID,
+		Assignee:   owners[0],
+		Source:     "ownership_rule",
+		RuleID:     rule.ID,
+		AssignedAt: time.Now(),
+	}, nil
+}
+
+func userExists(db *gorm.DB, userID string) bool {
+	var count int64
+
+	result := db.Table("users").Where("user_id = ?", userID).Count(&count)
+	if result.Error != nil {
+		return false
+	}
+
+	return count > 0
+}
+
+// normalizeAssignee validates a requested assignee, expanding "me" to the
+// requesting user.
+func normalizeAssignee(db *gorm.DB, assignee *string, userID string) (*string, error) {
+	if assignee == nil {
+		return nil, nil
+	}
+
+	owner := *assignee
+	if owner == "me" {
+		owner = "user:" + userID
+	}
+
+	if !ownership.ValidOwner(owner) {
+		return nil, errors.New("assignee must be user:<id>, team:<name> or me")
+	}
+
+	if id, isUser := strings.CutPrefix(owner, "user:"); isUser && !userExists(db, id) {
+		return nil, errors.New("User not found")
+	}
+
+	return &owner, nil
+}
+
+// setAssignee changes the assignee of an issue and logs it. It returns the
+// assignment to publish, if the assignee changed.
+func setAssignee(tx *gorm.DB, issue *models.Issue, assignee *string, userID string) (*IssueAssignmentEvent, error) {
+	previous := ""
+	if issue.Assignee != nil {
+		previous = *issue.Assignee
+	}
+
+	current := ""
+	if assignee != nil {
+		current = *assignee
+	}
+
+	if current == previous {
+		return nil, nil
+	}
+
+	err := tx.Model(issue).Update("assignee", assignee).Error
+	if err != nil {
+		return nil, err
+	}
+	issue.Assignee = assignee
+
+	activityType := ActivityAssigned
+	if current == "" {
+		activityType = ActivityUnassigned
+	}
+	err = recordActivity(tx, issue, activityType, userID, map[string]interface{}{
+		"assignee": current,
+		"previous": previous,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssueAssignmentEvent{
+		IssueID:          issue.ID,
+		ProjectID:        issue.ProjectID,
+		Assignee:         current,
+		PreviousAssignee: previous,
+		Source:           "user",
+		AssignedBy:       userID,
+		AssignedAt:       time.Now(),
+	}, nil
+}
+
+// AssignIssue sets or clears the assignee of an issue.
+func AssignIssue(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AssignIssueRequest
+
+		issue, ok := loadOwnedIssue(c, db)
+		if !ok {
+			return
+		}
+		userID := c.GetString("user_id")
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		assignee, err := normalizeAssignee(db, req.Assignee, userID)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		var assignment *IssueAssignmentEvent
+		err = db.Transaction(func(tx *gorm.DB) error {
+			assignment, err = setAssignee(tx, issue, assignee, userID)
+			return err
+		})
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to update issue"})
+			return
+		}
+
+		if assignment != nil {
+			publisher.PublishEvent(issueAssignmentWriter, issue.ProjectID, assignment)
+		}
+
+		c.JSON(200, gin.H{"issue": issue})
+	}
+}
+
+func GetOwnershipRules(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rules []models.OwnershipRule
+
+		projectID := c.Param("project_id")
+		userID := c.GetString("user_id")
+
+		if !verifyProjectOwnership(db, userID, projectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		err := db.Where("project_id = ?", projectID).Order("position, created_at").Find(&rules).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(200, gin.H{"rules": rules})
+	}
+}
+
+func CreateOwnershipRule(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateOwnershipRuleRequest
+
+		projectID := c.Param("project_id")
+		userID := c.GetString("user_id")
+
+		if !verifyProjectOwnership(db, userID, projectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		err = ownership.ValidateRule(ownership.Rule{Type: req.Type, Pattern: req.Pattern, Owners: req.Owners})
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		for _, owner := range req.Owners {
+			if id, isUser := strings.CutPrefix(owner, "user:"); isUser && !userExists(db, id) {
+				c.JSON(400, gin.H{"error": "User not found", "owner": owner})
+				return
+			}
+		}
+
+		rule := models.OwnershipRule{
+			ProjectID: projectID,
+			Type:      req.Type,
+			Pattern:   req.Pattern,
+			Owners:    req.Owners,
+		}
+
+		if req.Position != nil {
+			rule.Position = *req.Position
+		} else {
+			rule.Position, err = nextPosition(db, &models.OwnershipRule{}, projectID)
+			if err != nil {
+				c.JSON(500, gin.H{"error": "Internal server error"})
+				return
+			}
+		}
+
+		err = db.Create(&rule).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to create rule"})
+			return
+		}
+
+		c.JSON(201, gin.H{"rule": rule})
+	}
+}
+
+func DeleteOwnershipRule(db *gorm.DB) gin.HandlerFunc {
+	return deleteRule(db, &models.OwnershipRule{})
+}
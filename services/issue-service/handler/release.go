@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/k1ngalph0x/beacon/services/issue-service/models"
+	"github.com/k1ngalph0x/beacon/services/issue-service/releases"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// How many of the most recently seen releases are considered when looking
+// for a project's latest release.
+const latestReleaseWindow = 100
+
+func recordRelease(tx *gorm.DB, projectID, version string) error {
+	if version == "" {
+		return nil
+	}
+
+	now := time.Now()
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "version"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"last_seen": now}),
+	}).Create(&models.Release{ProjectID: projectID, Version: version, FirstSeen: now, LastSeen: now}).Error
+}
+
+// loadRelease returns a release for ordering. Releases never seen count as
+// first seen now, i.e. newer than any known one.
+func loadRelease(tx *gorm.DB, projectID, version string) (releases.Release, error) {
+	var release models.Release
+
+	err := tx.First(&release, "project_id = ? AND version = ?", projectID, version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return releases.Release{Version: version, FirstSeen: time.Now()}, nil
+	}
+	if err != nil {
+		return releases.Release{}, err
+	}
+	return releases.Release{Version: release.Version, FirstSeen: release.FirstSeen}, nil
+}
+
+// releaseOrder returns how a project's releases, together with versions it
+// may not have reported yet, are ordered.
+func releaseOrder(tx *gorm.DB, projectID string, versions ...string) (releases.Order, error) {
+	var known []string
+
+	err := tx.Model(&models.Release{}).Where("project_id = ?", projectID).Pluck("version", &known).Error
+	if err != nil {
+		return 0, err
+	}
+	return releases.OrderOf(append(known, versions...)), nil
+}
+
+// compareReleases orders two releases of a project like releases.Order.Compare.
+func compareReleases(tx *gorm.DB, projectID, a, b string) (int, error) {
+	if a == b {
+		return 0, nil
+	}
+
+	order, err := releaseOrder(tx, projectID, a, b)
+	if err != nil {
+		return 0, err
+	}
+	ra, err := loadRelease(tx, projectID, a)
+	if err != nil {
+		return 0, err
+	}
+	rb, err := loadRelease(tx, projectID, b)
+	if err != nil {
+		return 0, err
+	}
+	return order.Compare(ra, rb), nil
+}
+
+// latestRelease returns a project's newest release, or "" when it has not
+// reported any.
+func latestRelease(tx *gorm.DB, projectID string) (string, error) {
+	var rows []models.Release
+
+	err := tx.Where("project_id = ?", projectID).Order("last_seen desc").Limit(latestReleaseWindow).Find(&rows).Error
+	if err != nil {
+		return "", err
+	}
+
+	order, err := releaseOrder(tx, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	list := make([]releases.Release, len(rows))
+	for i, r := range rows {
+		list[i] = releases.Release{Version: r.Version, FirstSeen: r.FirstSeen}
+	}
+
+	latest, _ := order.Latest(list)
+	return latest.Version, nil
+}
+
+// regressedByRelease reports whether an event from release should regress
+// a resolved issue. Events without a release cannot be placed and only
+// regress issues resolved without one.
+func regressedByRelease(tx *gorm.DB, issue *models.Issue, release string) (bool, error) {
+	if issue.ResolvedInRelease == "" {
+		// Resolved in the next release before the project reported any:
+		// the first release seen stands in for the latest one at the time.
+		if issue.Status == StatusResolvedInNextRelease && release != "" {
+			issue.ResolvedInRelease = release
+			return false, tx.Model(issue).Update("resolved_in_release", release).Error
+		}
+		return issue.Status == StatusResolved, nil
+	}
+	if release == "" {
+		return false, nil
+	}
+
+	order, err := compareReleases(tx, issue.ProjectID, release, issue.ResolvedInRelease)
+	if err != nil {
+		return false, err
+	}
+
+	// Resolved in X: the fix shipped in X, so X or anything newer regresses.
+	// Resolved in the next release: X is the last release with the bug.
+	if issue.Status == StatusResolvedInNextRelease {
+		return order > 0, nil
+	}
+	return order >= 0, nil
+}
+
+func GetProjectReleases(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rows []models.Release
+
+		projectID := c.Param("project_id")
+		userID := c.GetString("user_id")
+
+		if !verifyProjectOwnership(db, userID, projectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		err := db.Where("project_id = ?", projectID).Order("last_seen desc").Limit(latestReleaseWindow).Find(&rows).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		order, err := releaseOrder(db, projectID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		list := make([]releases.Release, len(rows))
+		for i, r := range rows {
+			list[i] = releases.Release{Version: r.Version, FirstSeen: r.FirstSeen}
+		}
+		latest, _ := order.Latest(list)
+
+		c.JSON(200, gin.H{"releases": rows, "latest": latest.Version})
+	}
+}
@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/k1ngalph0x/beacon/services/issue-service/grouping"
+	"github.com/k1ngalph0x/beacon/services/issue-service/models"
+	"gorm.io/gorm"
+)
+
+type CreateFingerprintRuleRequest struct {
+	Matcher     string   `json:"matcher" binding:"required"`
+	Pattern     string   `json:"pattern" binding:"required"`
+	Fingerprint []string `json:"fingerprint" binding:"required"`
+	// Defaults to after the existing rules.
+	Position *int `json:"position"`
+}
+
+type CreateStackRuleRequest struct {
+	Matcher  string `json:"matcher" binding:"required"`
+	Pattern  string `json:"pattern" binding:"required"`
+	InApp    bool   `json:"in_app"`
+	Position *int   `json:"position"`
+}
+
+type DryRunRequest struct {
+	Message     string   `json:"message" binding:"required"`
+	StackTrace  *string  `json:"stack_trace"`
+	Fingerprint []string `json:"fingerprint"`
+	// Defaults to the project's grouping config.
+	GroupingConfig string `json:"grouping_config"`
+}
+
+// loadGroupingRules returns a project's fingerprint and stack rules in the
+// order they apply.
+func loadGroupingRules(db *gorm.DB, projectID string) (grouping.Rules, error) {
+	var rules grouping.Rules
+	var fingerprintRules []models.FingerprintRule
+	var stackRules []models.StackRule
+
+	err := db.Where("project_id = ?", projectID).Order("position, created_at").Find(&fingerprintRules).Error
+	if err != nil {
+		return rules, err
+	}
+
+	err = db.Where("project_id = ?", projectID).Order("position, created_at").Find(&stackRules).Error
+	if err != nil {
+		return rules, err
+	}
+
+	for _, r := range fingerprintRules {
+		rules.Fingerprint = append(rules.Fingerprint, grouping.NewFingerprintRule(r.ID, r.Matcher, r.Pattern, r.Fingerprint))
+	}
+	for _, r := range stackRules {
+		rules.Stack = append(rules.Stack, grouping.NewStackRule(r.ID, r.Matcher, r.Pattern, r.InApp))
+	}
+
+	return rules, nil
+}
+
+// nextPosition returns the position after the last rule of a project.
+func nextPosition(db *gorm.DB, model interface{}, projectID string) (int, error) {
+	var max *int
+	err := db.Model(model).Where("project_id = ?", projectID).Select("MAX(position)").Scan(&max).Error
+	if err != nil || max == nil {
+		return 0, err
+	}
+	return *max + 1, nil
+}
+
+func GetGroupingRules(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var fingerprintRules []models.FingerprintRule
+		var stackRules []models.StackRule
+
+		projectID := c.Param("project_id")
+		userID := c.GetString("user_id")
+
+		if !verifyProjectOwnership(db, userID, projectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		err := db.Where("project_id = ?", projectID).Order("position, created_at").Find(&fingerprintRules).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		err = db.Where("project_id = ?", projectID).Order("position, created_at").Find(&stackRules).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(200, gin.H{"fingerprint_rules": fingerprintRules, "stack_rules": stackRules})
+	}
+}
+
+func CreateFingerprintRule(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateFingerprintRuleRequest
+
+		projectID := c.Param("project_id")
+		userID := c.GetString("user_id")
+
+		if !verifyProjectOwnership(db, userID, projectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		err = grouping.ValidateRule(req.Matcher, req.Pattern, false)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(req.Fingerprint) == 0 {
+			c.JSON(400, gin.H{"error": "fingerprint is required"})
+			return
+		}
+
+		rule := models.FingerprintRule{
+			ProjectID:   projectID,
+			Matcher:     req.Matcher,
+			Pattern:     req.Pattern,
+			Fingerprint: req.Fingerprint,
+		}
+
+		if req.Position != nil {
+			rule.Position = *req.Position
+		} else {
+			rule.Position, err = nextPosition(db, &models.FingerprintRule{}, projectID)
+			if err != nil {
+				c.JSON(500, gin.H{"error": "Internal server error"})
+				return
+			}
+		}
+
+		err = db.Create(&rule).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to create rule"})
+			return
+		}
+
+		c.JSON(201, gin.H{"rule": rule})
+	}
+}
+
+func CreateStackRule(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateStackRuleRequest
+
+		projectID := c.Param("project_id")
+		userID := c.GetString("user_id")
+
+		if !verifyProjectOwnership(db, userID, projectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		err = grouping.ValidateRule(req.Matcher, req.Pattern, true)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		rule := models.StackRule{
+			ProjectID: projectID,
+			Matcher:   req.Matcher,
+			Pattern:   req.Pattern,
+			InApp:     req.InApp,
+		}
+
+		if req.Position != nil {
+			rule.Position = *req.Position
+		} else {
+			rule.Position, err = nextPosition(db, &models.StackRule{}, projectID)
+			if err != nil {
+				c.JSON(500, gin.H{"error": "Internal server error"})
+				return
+			}
+		}
+
+		err = db.Create(&rule).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to create rule"})
+			return
+		}
+
+		c.JSON(201, gin.H{"rule": rule})
+	}
+}
+
+// deleteRule returns a handler deleting one of a project's rules of the
+// given model.
+func deleteRule(db *gorm.DB, model interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		projectID := c.Param("project_id")
+		userID := c.GetString("user_id")
+
+		if !verifyProjectOwnership(db, userID, projectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		result := db.Where("id = ? AND project_id = ?", c.Param("rule_id"), projectID).Delete(model)
+		if result.Error != nil {
+			c.JSON(500, gin.H{"error": "Failed to delete rule"})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(404, gin.H{"error": "Rule not found"})
+			return
+		}
+
+		c.JSON(200, gin.H{"message": "Rule deleted"})
+	}
+}
+
+func DeleteFingerprintRule(db *gorm.DB) gin.HandlerFunc {
+	return deleteRule(db, &models.FingerprintRule{})
+}
+
+func DeleteStackRule(db *gorm.DB) gin.HandlerFunc {
+	return deleteRule(db, &models.StackRule{})
+}
+
+// DryRunGrouping groups a sample event with the project's current rules
+// without storing anything, and reports the issue it would join.
+func DryRunGrouping(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req DryRunRequest
+
+		projectID := c.Param("project_id")
+		userID := c.GetString("user_id")
+
+		if !verifyProjectOwnership(db, userID, projectID) {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		if req.GroupingConfig != "" && !grouping.Valid(req.GroupingConfig) {
+			c.JSON(400, gin.H{"error": "Invalid grouping config", "grouping_configs": grouping.Configs()})
+			return
+		}
+
+		settings, err := loadProjectSettings(db, projectID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		rules, err := loadGroupingRules(db, projectID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		if req.GroupingConfig != "" {
+			settings.GroupingConfig = req.GroupingConfig
+		}
+
+		result := grouping.Explain(settings.GroupingConfig, grouping.Input{
+			Message:     req.Message,
+			StackTrace:  req.StackTrace,
+			Fingerprint: req.Fingerprint,
+			Rules:       rules,
+		})
+
+		// Find the issue the way ProcessEvents would: through the fingerprint
+		// mappings, which follow merges, and the previous grouping config
+		// while a change is in transition.
+		fps := fingerprints(settings, rules, models.Event{
+			Message:     req.Message,
+			StackTrace:  req.StackTrace,
+			Fingerprint: req.Fingerprint,
+		})
+		issueID, _, err := mappedIssue(db, projectID, fps)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+		if issueID == "" {
+			c.JSON(200, gin.H{"result": result, "issue": nil})
+			return
+		}
+
+		var issue models.Issue
+		err = db.First(&issue, "id = ?", issueID).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(200, gin.H{"result": result, "issue": issue})
+	}
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/k1ngalph0x/beacon/services/issue-service/models"
+	"github.com/k1ngalph0x/beacon/services/issue-service/search"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultIssuePageSize = 25
+	maxIssuePageSize     = 100
+)
+
+// Columns issues can be sorted by, newest or largest first.
+var issueSorts = map[string]string{
+	"last_seen":  "last_seen",
+	"first_seen": "first_seen",
+	"count":      "count",
+	"times_seen": "count",
+	"user_count": "user_count",
+}
+
+// SearchError is a query the user has to fix.
+type SearchError struct {
+	Message string
+}
+
+func (e *SearchError) Error() string {
+	return e.Message
+}
+
+func searchErrorf(format string, args ...interface{}) error {
+	return &SearchError{Message: fmt.Sprintf(format, args...)}
+}
+
+var comparisonOps = map[string]string{
+	search.OpEqual:        "=",
+	search.OpGreater:      ">",
+	search.OpGreaterEqual: ">=",
+	search.OpLess:         "<",
+	search.OpLessEqual:    "<=",
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// statusCondition handles status:x and the is:x shortcuts for statuses.
+func statusCondition(value string) (string, []interface{}, bool) {
+	switch value {
+	case "unresolved":
+		return "issues.status IN ?", []interface{}{[]string{StatusOpen, StatusRegressed}}, true
+	case "resolved":
+		return "issues.status IN ?", []interface{}{[]string{StatusResolved, StatusResolvedInNextRelease}}, true
+	case "ignored":
+		return "issues.status IN ?", []interface{}{[]string{StatusIgnored, StatusIgnoredUntil}}, true
+	case StatusOpen, StatusRegressed, StatusResolvedInNextRelease, StatusIgnoredUntil:
+		return "issues.status = ?", []interface{}{value}, true
+	}
+	return "", nil, false
+}
+
+// filterCondition translates one filter into SQL. Keys that are not issue
+// fields match issue tags.
+func filterCondition(f search.Filter, userID string, now time.Time) (string, []interface{}, error) {
+	equalOnly := func() error {
+		if f.Op != search.OpEqual {
+			return searchErrorf("%s: comparisons are not supported", f.Key)
+		}
+		return nil
+	}
+
+	switch f.Key {
+	case "status", "is":
+		if err := equalOnly(); err != nil {
+			return "", nil, err
+		}
+
+		if f.Key == "is" {
+			switch f.Value {
+			case "assigned":
+				return "issues.assignee IS NOT NULL", nil, nil
+			case "unassigned":
+				return "issues.assignee IS NULL", nil, nil
+			}
+		}
+
+		var conds []string
+		var args []interface{}
+		for _, value := range strings.Split(f.Value, ",") {
+			cond, condArgs, ok := statusCondition(value)
+			if !ok {
+				return "", nil, searchErrorf("%s: unknown value %q", f.Key, value)
+			}
+			conds = append(conds, cond)
+			args = append(args, condArgs...)
+		}
+		return "(" + strings.Join(conds, " OR ") + ")", args, nil
+
+	case "level":
+		if err := equalOnly(); err != nil {
+			return "", nil, err
+		}
+		return "issues.level IN ?", []interface{}{strings.Split(f.Value, ",")}, nil
+
+	case "assigned":
+		if err := equalOnly(); err != nil {
+			return "", nil, err
+		}
+		if f.Value == "me" {
+			return "issues.assignee = ?", []interface{}{"user:" + userID}, nil
+		}
+		return "issues.assignee = ?", []interface{}{f.Value}, nil
+
+	case "times_seen", "count", "user_count":
+		n, err := strconv.Atoi(f.Value)
+		if err != nil {
+			return "", nil, searchErrorf("%s: %q is not a number", f.Key, f.Value)
+		}
+		column := "issues.count"
+		if f.Key == "user_count" {
+			column = "issues.user_count"
+		}
+		return column + " " + comparisonOps[f.Op] + " ?", []interface{}{n}, nil
+
+	case "first_seen", "last_seen":
+		op, t, err := search.TimeBound(f, now)
+		if err != nil {
+			return "", nil, searchErrorf("%s: %v", f.Key, err)
+		}
+		return "issues." + f.Key + " " + comparisonOps[op] + " ?", []interface{}{t}, nil
+	}
+
+	if err := equalOnly(); err != nil {
+		return "", nil, err
+	}
+	return "EXISTS (SELECT 1 FROM issue_tags WHERE issue_tags.issue_id = issues.id AND issue_tags.key = ? AND issue_tags.value IN ?)",
+		[]interface{}{f.Key, strings.Split(f.Value, ",")}, nil
+}
+
+// issueSearchQuery returns the issues of the given projects matching a
+// search query. Merged issues are never returned.
+func issueSearchQuery(db *gorm.DB, projectIDs []string, q, userID string) (*gorm.DB, error) {
+	query, err := search.Parse(q)
+	if err != nil {
+		return nil, &SearchError{Message: err.Error()}
+	}
+
+	tx := db.Model(&models.Issue{}).Where("issues.project_id IN ? AND issues.merged_into_id IS NULL", projectIDs)
+
+	now := time.Now()
+	for _, f := range query.Filters {
+		cond, args, err := filterCondition(f, userID, now)
+		if err != nil {
+			return nil, err
+		}
+		if f.Negate {
+			cond = "NOT (" + cond + ")"
+		}
+		tx = tx.Where(cond, args...)
+	}
+
+	for _, text := range query.Text {
+		tx = tx.Where("issues.title ILIKE ?", "%"+escapeLike(text)+"%")
+	}
+
+	return tx, nil
+}
+
+// issueCursor encodes the position after an issue in a sort order.
+func issueCursor(issue models.Issue, sort string) string {
+	var value string
+	switch sort {
+	case "first_seen":
+		value = issue.FirstSeen.UTC().Format(time.RFC3339Nano)
+	case "count":
+		value = strconv.Itoa(issue.Count)
+	case "user_count":
+		value = strconv.Itoa(issue.UserCount)
+	default:
+		value = issue.LastSeen.UTC().Format(time.RFC3339Nano)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(value + "," + issue.ID))
+}
+
+func parseIssueCursor(cursor, sort string) (interface{}, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	value, id, ok := strings.Cut(string(raw), ",")
+	if _, err := uuid.Parse(id); !ok || err != nil {
+		return nil, "", errors.New("malformed cursor")
+	}
+
+	switch sort {
+	case "count", "user_count":
+		n, err := strconv.Atoi(value)
+		return n, id, err
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	return t, id, err
+}
+
+// IssuePage is one page of search results.
+type IssuePage struct {
+	Issues     []models.Issue
+	NextCursor string
+}
+
+// searchIssues runs a search with sorting and keyset pagination.
+func searchIssues(db *gorm.DB, projectIDs []string, q, sortBy, cursor string, limit int, userID string) (IssuePage, error) {
+	var page IssuePage
+
+	if sortBy == "" {
+		sortBy = "last_seen"
+	}
+	column, ok := issueSorts[sortBy]
+	if !ok {
+		return page, searchErrorf("sort must be one of last_seen, first_seen, count, user_count")
+	}
+
+	if limit <= 0 {
+		limit = defaultIssuePageSize
+	}
+	limit = min(limit, maxIssuePageSize)
+
+	tx, err := issueSearchQuery(db, projectIDs, q, userID)
+	if err != nil {
+		return page, err
+	}
+
+	if cursor != "" {
+		value, id, err := parseIssueCursor(cursor, column)
+		if err != nil {
+			return page, searchErrorf("invalid cursor")
+		}
+		tx = tx.Where("(issues."+column+", issues.id) < (?, ?)", value, id)
+	}
+
+	// One extra row tells whether there is another page.
+	err = tx.Order("issues." + column + " DESC, issues.id DESC").Limit(limit + 1).Find(&page.Issues).Error
+	if err != nil {
+		return page, err
+	}
+
+	if len(page.Issues) > limit {
+		page.Issues = page.Issues[:limit]
+		page.NextCursor = issueCursor(page.Issues[limit-1], column)
+	}
+
+	return page, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/k1ngalph0x/beacon/services/issue-service/config"
@@ -28,51 +29,312 @@ func main() {
 		log.Fatalf("DB error: %v", err)
 	}
 
-	err = conn.AutoMigrate(&models.Issue{})
+	err = conn.AutoMigrate(&models.Issue{}, &models.ProcessedEvent{}, &models.ProjectSettings{}, &models.FingerprintRule{}, &models.StackRule{}, &models.IssueFingerprint{}, &models.IssueEvent{}, &models.IssueUser{}, &models.IssueTag{}, &models.Release{}, &models.OwnershipRule{}, &models.IssueComment{}, &models.IssueActivity{})
 	if err != nil {
 		log.Fatalf("Migration error: %v", err)
 	}
 
+	// Issues created before fingerprint mappings existed own their
+	// fingerprint.
+	err = conn.Exec(`INSERT INTO issue_fingerprints (project_id, fingerprint, issue_id, created_at)
+		SELECT project_id, fingerprint, id, created_at FROM issues
+		ON CONFLICT DO NOTHING`).Error
+	if err != nil {
+		log.Fatalf("Migration error: %v", err)
+	}
+
+	// Issues that had users before user_count was maintained.
+	err = conn.Exec(`UPDATE issues SET user_count = users.n
+		FROM (SELECT issue_id, COUNT(*) AS n FROM issue_users GROUP BY issue_id) users
+		WHERE issues.id = users.issue_id AND issues.user_count = 0`).Error
+	if err != nil {
+		log.Fatalf("Migration error: %v", err)
+	}
+
+	// Trigram index for free-text title search. It needs the pg_trgm
+	// extension; without it search still works, only slower.
+	err = conn.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error
+	if err == nil {
+		err = conn.Exec("CREATE INDEX IF NOT EXISTS idx_issues_title_trgm ON issues USING gin (title gin_trgm_ops)").Error
+	}
+	if err != nil {
+		log.Println("Title search index not created:", err)
+	}
+
 	authMiddleware := middleware.NewAuthMiddleware(config.TOKEN.JwtKey)
 
-	go startKafkaConsumer(conn)
-	startHTTPServer(conn, authMiddleware)
-}
+	handler.SetBrokers(config.KAFKA.Brokers)
 
+	go startKafkaConsumer(conn, config.KAFKA.Brokers, config.BATCH, config.RETRY)
+	go startDedupPruner(conn, config.DEDUP.Retention)
+	go startIgnoreSweeper(conn)
+	startHTTPServer(conn, authMiddleware, config)
+}
 
-func startKafkaConsumer(conn *gorm.DB) {
 
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{"localhost:9092"},
+func newEventReader(brokers []string) *kafka.Reader {
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
 		Topic:   "beacon-events",
 		GroupID: "issue-consumers",
 	})
+}
+
+func startKafkaConsumer(conn *gorm.DB, brokers []string, batch config.BatchConfig, retry config.RetryConfig) {
+
+	reader := newEventReader(brokers)
+
+	// Messages that were neither written nor dead-lettered, tried again
+	// first in the next batch.
+	var held []kafka.Message
 
 	for {
-		msg, err := reader.ReadMessage(context.Background())
+		msgs, err := readBatch(reader, batch, held)
 		if err != nil {
 			log.Println("Kafka read error:", err)
+		}
+		if len(msgs) == 0 {
 			continue
 		}
 
-		var event models.Event
-		if err := json.Unmarshal(msg.Value, &event); err != nil {
-			log.Println("Invalid event:", err)
+		// A message is handled once it is written or dead-lettered.
+		handled := make([]bool, len(msgs))
+
+		var events []models.Event
+		var sources []int
+		for i, msg := range msgs {
+			var event models.Event
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				handled[i] = deadLetter(msg, "invalid event: "+err.Error(), 1)
+				continue
+			}
+			events = append(events, event)
+			sources = append(sources, i)
+		}
+
+		if len(events) > 0 {
+			errs := make([]error, len(events))
+			err = processWithRetry(conn, events, retry)
+			switch {
+			case err == nil:
+			case len(events) == 1:
+				errs[0] = err
+			default:
+				// Split the batch, so a bad event only costs itself.
+				log.Printf("Failed to process batch of %d events, splitting it: %v", len(events), err)
+				errs = processSplit(conn, events, retry)
+			}
+
+			for j, err := range errs {
+				i := sources[j]
+				handled[i] = err == nil || deadLetter(msgs[i], err.Error(), retry.Attempts)
+			}
+		}
+
+		held = commitHandled(reader, msgs, handled)
+		if len(held) > 0 {
+			time.Sleep(retry.Backoff)
+		}
+	}
+
+}
+
+func deadLetter(msg kafka.Message, reason string, attempts int) bool {
+	err := handler.DeadLetter(msg, reason, attempts)
+	if err != nil {
+		log.Printf("Failed to dead-letter message %d@%d: %v", msg.Partition, msg.Offset, err)
+		return false
+	}
+	return true
+}
+
+// commitHandled commits, per partition, the messages before the first one
+// left unhandled, so offsets never move past an event that was neither
+// written nor dead-lettered. It returns the unhandled messages, to be tried
+// again. The reader keeps its partitions meanwhile: they are rewound in
+// memory rather than by reopening it, which would rebalance the group.
+func commitHandled(reader *kafka.Reader, msgs []kafka.Message, handled []bool) []kafka.Message {
+	blocked := map[int]bool{}
+	var commit, held []kafka.Message
+
+	for i, msg := range msgs {
+		if !handled[i] {
+			blocked[msg.Partition] = true
+			held = append(held, msg)
 			continue
 		}
+		if !blocked[msg.Partition] {
+			commit = append(commit, msg)
+		}
+	}
 
-		handler.ProcessEvent(conn, event)
+	if len(commit) > 0 {
+		err := reader.CommitMessages(context.Background(), commit...)
+		if err != nil {
+			log.Println("Kafka commit error:", err)
+		}
 	}
 
+	return held
 }
 
-func startHTTPServer(db *gorm.DB, authMiddleware  *middleware.AuthMiddleware) {
+// processWithRetry processes events, retrying failures with exponential
+// backoff. While the database is unreachable it waits instead of using up
+// attempts, so an outage is not mistaken for bad events.
+func processWithRetry(conn *gorm.DB, events []models.Event, retry config.RetryConfig) error {
+	backoff := retry.Backoff
+
+	for attempt := 1; ; attempt++ {
+		err := handler.ProcessEvents(conn, events)
+		if err == nil {
+			return nil
+		}
+
+		if !dbAvailable(conn) {
+			waitForDB(conn)
+			attempt, backoff = 0, retry.Backoff
+			continue
+		}
+
+		if attempt >= retry.Attempts {
+			return err
+		}
+
+		log.Printf("Processing failed (attempt %d of %d), retrying: %v", attempt, retry.Attempts, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// Wait before retrying a single event split out of a failed batch. The
+// batch was already retried with the full backoff, so a failure that
+// remains is most likely the event's own.
+const singleEventBackoff = 100 * time.Millisecond
+
+// processSplit processes a failed batch by halves, tried once each, so the
+// events causing the failure are found without retrying every event with
+// the full backoff. Single events left failing get a few quick retries. It
+// returns the error of each event, nil for those processed.
+func processSplit(conn *gorm.DB, events []models.Event, retry config.RetryConfig) []error {
+	errs := make([]error, len(events))
+	if len(events) == 1 {
+		errs[0] = processWithRetry(conn, events, config.RetryConfig{Attempts: retry.Attempts, Backoff: singleEventBackoff})
+		return errs
+	}
+
+	mid := len(events) / 2
+	for _, half := range [][2]int{{0, mid}, {mid, len(events)}} {
+		part := events[half[0]:half[1]]
+		if len(part) > 1 && processWithRetry(conn, part, config.RetryConfig{Attempts: 1}) == nil {
+			continue
+		}
+		copy(errs[half[0]:half[1]], processSplit(conn, part, retry))
+	}
+	return errs
+}
+
+func dbAvailable(conn *gorm.DB) bool {
+	sqlDB, err := conn.DB()
+	return err == nil && sqlDB.Ping() == nil
+}
+
+func waitForDB(conn *gorm.DB) {
+	log.Println("Database unavailable, pausing event processing")
+	for !dbAvailable(conn) {
+		time.Sleep(5 * time.Second)
+	}
+	log.Println("Database available, resuming event processing")
+}
+
+// readBatch starts a batch with the messages held back from the previous
+// one, or waits for a message, then collects more until the batch is full
+// or its window has passed.
+func readBatch(reader *kafka.Reader, batch config.BatchConfig, held []kafka.Message) ([]kafka.Message, error) {
+	msgs := held
+	if len(msgs) == 0 {
+		msg, err := reader.FetchMessage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		msgs = []kafka.Message{msg}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), batch.Window)
+	defer cancel()
+
+	for len(msgs) < batch.Size {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil && ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, nil
+}
+
+func startDedupPruner(conn *gorm.DB, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-retention)
+		result := conn.Where("processed_at < ?", cutoff).Delete(&models.ProcessedEvent{})
+		if result.Error != nil {
+			log.Println("Failed to prune processed events:", result.Error)
+		}
+	}
+}
+
+func startIgnoreSweeper(conn *gorm.DB) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := handler.ReopenExpiredIgnores(conn)
+		if err != nil {
+			log.Println("Failed to reopen expired ignores:", err)
+		}
+	}
+}
+
+func startHTTPServer(db *gorm.DB, authMiddleware  *middleware.AuthMiddleware, config *config.Config) {
 	router := gin.Default()
 
 	router.Use(authMiddleware.RequireAuth())
 	router.GET("/projects/:project_id/issues", handler.GetProjectIssue(db))
 	router.GET("/issues/:id", handler.GetIssue(db))
+	router.GET("/issues/:id/events", handler.GetIssueEvents(db))
+	router.GET("/issues/:id/events/latest", handler.GetLatestIssueEvent(db))
+	router.GET("/issues/:id/events/oldest", handler.GetOldestIssueEvent(db))
 	router.PATCH("/issues/:id/resolve", handler.ResolveIssue(db))
+	router.PATCH("/issues/:id/status", handler.UpdateIssueStatus(db))
+	router.PUT("/issues/:id/assignee", handler.AssignIssue(db))
+	router.GET("/issues/:id/activity", handler.GetIssueActivity(db))
+	router.GET("/issues/:id/comments", handler.GetIssueComments(db))
+	router.POST("/issues/:id/comments", handler.CreateIssueComment(db))
+	router.DELETE("/issues/:id/comments/:comment_id", handler.DeleteIssueComment(db))
+	router.POST("/issues/merge", handler.MergeIssues(db))
+	router.POST("/issues/bulk", handler.BulkUpdateIssues(db))
+	router.POST("/issues/:id/unmerge", handler.UnmergeIssue(db))
+	router.GET("/projects/:project_id/releases", handler.GetProjectReleases(db))
+	router.GET("/projects/:project_id/ownership-rules", handler.GetOwnershipRules(db))
+	router.POST("/projects/:project_id/ownership-rules", handler.CreateOwnershipRule(db))
+	router.DELETE("/projects/:project_id/ownership-rules/:rule_id", handler.DeleteOwnershipRule(db))
+	router.GET("/projects/:project_id/grouping", handler.GetGroupingSettings(db))
+	router.PUT("/projects/:project_id/grouping", handler.UpdateGroupingSettings(db, config.GROUPING.Transition))
+	router.GET("/projects/:project_id/grouping/rules", handler.GetGroupingRules(db))
+	router.POST("/projects/:project_id/grouping/fingerprint-rules", handler.CreateFingerprintRule(db))
+	router.DELETE("/projects/:project_id/grouping/fingerprint-rules/:rule_id", handler.DeleteFingerprintRule(db))
+	router.POST("/projects/:project_id/grouping/stack-rules", handler.CreateStackRule(db))
+	router.DELETE("/projects/:project_id/grouping/stack-rules/:rule_id", handler.DeleteStackRule(db))
+	router.POST("/projects/:project_id/grouping/dry-run", handler.DryRunGrouping(db))
+
+	admin := router.Group("/admin", middleware.RequireAdmin(config.ADMIN.UserIDs))
+	admin.POST("/dlq/replay", handler.ReplayDeadLetters())
 
 	log.Println("Issue API running on :8094")
 	router.Run(":8094")
@@ -0,0 +1,21 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// RequireAdmin only lets the given users through. It runs after RequireAuth.
+func RequireAdmin(userIDs []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		admins[id] = true
+	}
+
+	return func(c *gin.Context) {
+		if !admins[c.GetString("user_id")] {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
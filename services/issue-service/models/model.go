@@ -4,30 +4,208 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
 type Issue struct {
 	ID          string `gorm:"type:uuid;primaryKey"`
 	Fingerprint string `gorm:"uniqueIndex:idx_project_fp"`
-	ProjectID   string `gorm:"uniqueIndex:idx_project_fp"`
+	// Indexed with each sort column for issue search.
+	ProjectID   string `gorm:"uniqueIndex:idx_project_fp;index:idx_issues_project_last_seen,priority:1;index:idx_issues_project_first_seen,priority:1;index:idx_issues_project_count,priority:1;index:idx_issues_project_user_count,priority:1"`
 	Title       string
 	Level       string
-	Count       int
-	FirstSeen   time.Time
-	LastSeen    time.Time
+	Count       int       `gorm:"index:idx_issues_project_count,priority:2"`
+	// Number of distinct users in IssueUser.
+	UserCount   int       `gorm:"not null;default:0;index:idx_issues_project_user_count,priority:2"`
+	FirstSeen   time.Time `gorm:"index:idx_issues_project_first_seen,priority:2"`
+	LastSeen    time.Time `gorm:"index:idx_issues_project_last_seen,priority:2"`
 	Status      string
+	// Owner of the issue, "user:<id>" or "team:<name>"; nil when unassigned.
+	Assignee    *string
+	// Grouping configuration that produced Fingerprint, e.g. "stack:v1".
+	GroupingConfig string `gorm:"not null;default:'legacy:v1'"`
+	// Set once the issue has been merged into another. Its fingerprints
+	// and events then belong to that issue.
+	MergedIntoID *string `gorm:"type:uuid;index"`
+	// Latest release the issue was seen in.
+	LastRelease string
+	ResolvedAt  *time.Time
+	// Release that fixed a resolved issue, so events from older releases do
+	// not regress it; for resolved_in_next_release, the latest release when
+	// it was resolved.
+	ResolvedInRelease string
+	IgnoredAt         *time.Time
+	// Conditions ending an ignored_until status: a time, a number of events
+	// since IgnoredAt, or a number of new users since IgnoredAt.
+	IgnoreUntil     *time.Time
+	IgnoreCount     *int
+	IgnoreUserCount *int
+	// Count when the issue was ignored, for IgnoreCount.
+	IgnoreBaseCount int
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
 
+// IssueFingerprint maps a fingerprint to the issue its events join. An
+// issue owns the fingerprint it was created with plus those of the issues
+// merged into it.
+type IssueFingerprint struct {
+	ProjectID   string    `gorm:"primaryKey" json:"project_id"`
+	Fingerprint string    `gorm:"primaryKey" json:"fingerprint"`
+	IssueID     string    `gorm:"type:uuid;not null;index" json:"issue_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IssueEvent links a processed event to the issue it was counted towards
+// and the fingerprint it had, so issues can be split again. The user and
+// tags the event counted towards are kept to rebuild IssueUser and IssueTag
+// when they are. Clients choose event IDs, so they are only unique within a
+// project.
+type IssueEvent struct {
+	ProjectID   string            `gorm:"primaryKey" json:"project_id"`
+	EventID     string            `gorm:"type:uuid;primaryKey" json:"event_id"`
+	IssueID     string            `gorm:"type:uuid;not null;index:idx_issue_events_issue_fp;index:idx_issue_events_issue_ts,priority:1" json:"issue_id"`
+	Fingerprint string            `gorm:"not null;index:idx_issue_events_issue_fp" json:"fingerprint"`
+	Timestamp   time.Time         `gorm:"not null;index:idx_issue_events_issue_ts,priority:2" json:"timestamp"`
+	UserKey     string            `gorm:"not null;default:''" json:"user_key,omitempty"`
+	Tags        map[string]string `gorm:"type:jsonb;serializer:json" json:"tags,omitempty"`
+}
+
+// Release is a version a project has reported events from. Versions that
+// are not semantic versions are ordered by FirstSeen.
+type Release struct {
+	ProjectID string    `gorm:"primaryKey" json:"project_id"`
+	Version   string    `gorm:"primaryKey" json:"version"`
+	FirstSeen time.Time `gorm:"not null" json:"first_seen"`
+	LastSeen  time.Time `gorm:"not null" json:"last_seen"`
+}
+
+// IssueTag aggregates the values of a tag across an issue's events. Release
+// and environment are recorded as tags too.
+type IssueTag struct {
+	IssueID   string    `gorm:"type:uuid;primaryKey" json:"issue_id"`
+	Key       string    `gorm:"primaryKey;index:idx_issue_tags_key_value,priority:1" json:"key"`
+	Value     string    `gorm:"primaryKey;index:idx_issue_tags_key_value,priority:2" json:"value"`
+	Count     int       `gorm:"not null" json:"count"`
+	FirstSeen time.Time `gorm:"not null" json:"first_seen"`
+	LastSeen  time.Time `gorm:"not null" json:"last_seen"`
+}
+
+// IssueUser records each user affected by an issue, identified by the
+// event's user ID, email, username or IP address.
+type IssueUser struct {
+	IssueID   string    `gorm:"type:uuid;primaryKey" json:"issue_id"`
+	UserKey   string    `gorm:"primaryKey" json:"user_key"`
+	FirstSeen time.Time `gorm:"not null" json:"first_seen"`
+	LastSeen  time.Time `gorm:"not null" json:"last_seen"`
+}
+
+// ProjectSettings holds per-project issue settings. When the grouping
+// config changes, the previous one is kept until TransitionUntil so events
+// keep joining issues created under it instead of opening new ones.
+type ProjectSettings struct {
+	ProjectID              string     `gorm:"type:uuid;primaryKey" json:"project_id"`
+	GroupingConfig         string     `gorm:"not null" json:"grouping_config"`
+	PreviousGroupingConfig string     `json:"previous_grouping_config,omitempty"`
+	TransitionUntil        *time.Time `json:"transition_until,omitempty"`
+	UpdatedAt              time.Time  `json:"updated_at"`
+}
+
+// FingerprintRule assigns a fingerprint to a project's events matching
+// Pattern. Rules are tried by ascending Position and the first match wins.
+type FingerprintRule struct {
+	ID          string         `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID   string         `gorm:"type:uuid;not null;index" json:"project_id"`
+	Matcher     string         `gorm:"not null" json:"matcher"`
+	Pattern     string         `gorm:"not null" json:"pattern"`
+	Fingerprint pq.StringArray `gorm:"type:text[];not null" json:"fingerprint"`
+	Position    int            `gorm:"not null" json:"position"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// StackRule overrides whether frames matching Pattern are in-app. Rules
+// apply by ascending Position, so later rules win.
+type StackRule struct {
+	ID        string    `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID string    `gorm:"type:uuid;not null;index" json:"project_id"`
+	Matcher   string    `gorm:"not null" json:"matcher"`
+	Pattern   string    `gorm:"not null" json:"pattern"`
+	InApp     bool      `gorm:"not null" json:"in_app"`
+	Position  int       `gorm:"not null" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OwnershipRule assigns issues whose events match Pattern to Owners
+// ("user:<id>" or "team:<name>"). As in CODEOWNERS, the matching rule with
+// the highest Position wins and its first owner becomes the assignee.
+type OwnershipRule struct {
+	ID        string         `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID string         `gorm:"type:uuid;not null;index" json:"project_id"`
+	Type      string         `gorm:"not null" json:"type"`
+	Pattern   string         `gorm:"not null" json:"pattern"`
+	Owners    pq.StringArray `gorm:"type:text[];not null" json:"owners"`
+	Position  int            `gorm:"not null" json:"position"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// IssueComment is a message in an issue's discussion thread.
+type IssueComment struct {
+	ID        string    `gorm:"type:uuid;primaryKey" json:"id"`
+	IssueID   string    `gorm:"type:uuid;not null;index:idx_issue_comments_issue_created,priority:1" json:"issue_id"`
+	UserID    string    `gorm:"type:uuid;not null" json:"user_id"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt time.Time `gorm:"index:idx_issue_comments_issue_created,priority:2" json:"created_at"`
+}
+
+// IssueActivity is an entry in an issue's append-only activity log. UserID
+// is nil for changes made by issue-service itself.
+type IssueActivity struct {
+	ID        string                 `gorm:"type:uuid;primaryKey" json:"id"`
+	IssueID   string                 `gorm:"type:uuid;not null;index:idx_issue_activities_issue_created,priority:1" json:"issue_id"`
+	ProjectID string                 `gorm:"not null" json:"project_id"`
+	Type      string                 `gorm:"not null" json:"type"`
+	UserID    *string                `gorm:"type:uuid" json:"user_id"`
+	Data      map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"data,omitempty"`
+	CreatedAt time.Time              `gorm:"index:idx_issue_activities_issue_created,priority:2" json:"created_at"`
+}
+
+// ProcessedEvent records event IDs already counted towards an issue so that
+// Kafka redeliveries and SDK retries are only counted once. IDs are keyed
+// per project, so one project cannot suppress another's events by reusing
+// their IDs. Rows older than the dedup retention window are pruned.
+type ProcessedEvent struct {
+	ProjectID   string    `gorm:"primaryKey"`
+	EventID     string    `gorm:"type:uuid;primaryKey"`
+	ProcessedAt time.Time `gorm:"not null;index"`
+}
 
 type Event struct {
+	EventID    string     `json:"event_id"`
 	ProjectID  string     `json:"project_id"`
 	Timestamp  time.Time  `json:"timestamp"`
 	Level      string     `json:"level"`
 	Message    string     `json:"message"`
 	StackTrace *string    `json:"stack_trace"`
+	Fingerprint []string  `json:"fingerprint"`
+	Release    string     `json:"release"`
+	Environment string    `json:"environment"`
+	Tags       map[string]string `json:"tags"`
+	User       *EventUser `json:"user"`
+	Client     *EventClient `json:"client"`
+}
+
+type EventUser struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+	IPAddress string `json:"ip_address"`
+}
+
+// EventClient is the part of the client ingestion describes that issue
+// service uses.
+type EventClient struct {
+	IP string `json:"ip"`
 }
 
 func (i *Issue) BeforeCreate(tx *gorm.DB) error{
@@ -36,4 +214,39 @@ func (i *Issue) BeforeCreate(tx *gorm.DB) error{
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func (r *FingerprintRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (r *StackRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (r *OwnershipRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (c *IssueComment) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (a *IssueActivity) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Package ownership picks the owner of an issue from CODEOWNERS-style
+// rules: the last rule matching the event wins.
+package ownership
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/k1ngalph0x/beacon/services/issue-service/grouping"
+)
+
+// Rule types.
+const (
+	// Path matches file paths of in-app frames. Like CODEOWNERS, a pattern
+	// without a leading "/" matches at any depth and a trailing "/" matches
+	// everything below a directory.
+	Path = "path"
+	// Module matches the module of in-app frames.
+	Module = "module"
+	// Tag matches an event tag, as "key:value-pattern".
+	Tag = "tag"
+	// URL matches the event's "url" tag.
+	URL = "url"
+)
+
+// Rule is an ownership rule. Create rules to match with NewRule.
+type Rule struct {
+	ID      string
+	Type    string
+	Pattern string
+	Owners  []string
+
+	// The tag a Tag rule looks at, and the compiled patterns of the rule.
+	tagKey string
+	globs  []grouping.Glob
+}
+
+// NewRule compiles a rule's pattern once, for matching against many events.
+func NewRule(id, ruleType, pattern string, owners []string) Rule {
+	r := Rule{ID: id, Type: ruleType, Pattern: pattern, Owners: owners}
+
+	switch ruleType {
+	case Tag:
+		key, value, _ := strings.Cut(pattern, ":")
+		r.tagKey = key
+		r.globs = []grouping.Glob{grouping.CompileGlob(value)}
+	case Path:
+		r.globs = pathGlobs(pattern)
+	default:
+		r.globs = []grouping.Glob{grouping.CompileGlob(pattern)}
+	}
+
+	return r
+}
+
+// Event is the part of an event ownership looks at.
+type Event struct {
+	Frames []grouping.Frame
+	Tags   map[string]string
+}
+
+// ValidOwner reports whether owner has the form "user:<id>" or
+// "team:<name>".
+func ValidOwner(owner string) bool {
+	kind, name, ok := strings.Cut(owner, ":")
+	return ok && name != "" && (kind == "user" || kind == "team")
+}
+
+// ValidateRule checks a rule before it is stored.
+func ValidateRule(r Rule) error {
+	switch r.Type {
+	case Path, Module, URL:
+	case Tag:
+		if key, _, ok := strings.Cut(r.Pattern, ":"); !ok || key == "" {
+			return errors.New("tag patterns have the form key:value")
+		}
+	default:
+		return errors.New("type must be one of path, module, tag, url")
+	}
+
+	if strings.TrimSpace(r.Pattern) == "" {
+		return errors.New("pattern is required")
+	}
+
+	if len(r.Owners) == 0 {
+		return errors.New("at least one owner is required")
+	}
+	for _, owner := range r.Owners {
+		if !ValidOwner(owner) {
+			return errors.New("owners have the form user:<id> or team:<name>")
+		}
+	}
+
+	return nil
+}
+
+// pathGlobs expands a path pattern into the globs matching it: the pattern
+// itself and, unless anchored, the pattern below any directory.
+func pathGlobs(pattern string) []grouping.Glob {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "*"
+	}
+
+	globs := []grouping.Glob{grouping.CompileGlob(pattern)}
+	if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "*") {
+		globs = append(globs, grouping.CompileGlob("*/"+pattern))
+	}
+	return globs
+}
+
+func (r Rule) matchAny(s string) bool {
+	for _, glob := range r.globs {
+		if glob.Match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// frames returns the in-app frames, or all frames when none is in-app.
+func frames(e Event) []grouping.Frame {
+	var inApp []grouping.Frame
+	for _, f := range e.Frames {
+		if f.InApp {
+			inApp = append(inApp, f)
+		}
+	}
+	if len(inApp) == 0 {
+		return e.Frames
+	}
+	return inApp
+}
+
+func (r Rule) matches(e Event) bool {
+	switch r.Type {
+	case Tag:
+		value, ok := e.Tags[r.tagKey]
+		return ok && r.matchAny(value)
+	case URL:
+		value, ok := e.Tags["url"]
+		return ok && r.matchAny(value)
+	}
+
+	for _, f := range frames(e) {
+		switch {
+		case r.Type == Path && f.File != "" && r.matchAny(f.File):
+			return true
+		case r.Type == Module && f.Module != "" && r.matchAny(f.Module):
+			return true
+		}
+	}
+	return false
+}
+
+// Owners returns the owners of the last rule matching an event, and that
+// rule, or false when none matches.
+func Owners(rules []Rule, e Event) ([]string, Rule, bool) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].matches(e) {
+			return rules[i].Owners, rules[i], true
+		}
+	}
+	return nil, Rule{}, false
+}
@@ -0,0 +1,119 @@
+package ownership
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/k1ngalph0x/beacon/services/issue-service/grouping"
+)
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "path rule", rule: Rule{Type: Path, Pattern: "payments/", Owners: []string{"team:billing"}}},
+		{name: "tag rule", rule: Rule{Type: Tag, Pattern: "customer:enterprise-*", Owners: []string{"user:42"}}},
+		{name: "tag rule without key", rule: Rule{Type: Tag, Pattern: ":x", Owners: []string{"team:a"}}, wantErr: true},
+		{name: "unknown type", rule: Rule{Type: "file", Pattern: "x", Owners: []string{"team:a"}}, wantErr: true},
+		{name: "blank pattern", rule: Rule{Type: Module, Pattern: " ", Owners: []string{"team:a"}}, wantErr: true},
+		{name: "no owners", rule: Rule{Type: URL, Pattern: "*/pay"}, wantErr: true},
+		{name: "malformed owner", rule: Rule{Type: URL, Pattern: "*/pay", Owners: []string{"alice"}}, wantErr: true},
+		{name: "owner without name", rule: Rule{Type: URL, Pattern: "*/pay", Owners: []string{"user:"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRule(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRule error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOwners(t *testing.T) {
+	event := Event{
+		Frames: []grouping.Frame{
+			{Function: "pq.query", Module: "github.com/lib/pq", File: "/go/pkg/mod/github.com/lib/pq/conn.go"},
+			{Function: "billing.Charge", Module: "github.com/acme/app/billing", File: "/srv/app/payments/charge.go", InApp: true},
+		},
+		Tags: map[string]string{"customer": "enterprise-7", "url": "https://acme.test/pay"},
+	}
+
+	tests := []struct {
+		name     string
+		rules    []Rule
+		event    Event
+		wantRule string
+	}{
+		{
+			name:     "unanchored directory",
+			rules:    []Rule{NewRule("r1", Path, "payments/", []string{"team:billing"})},
+			event:    event,
+			wantRule: "r1",
+		},
+		{
+			name:  "anchored path must match from the root",
+			rules: []Rule{NewRule("r1", Path, "/payments/", []string{"team:billing"})},
+			event: event,
+		},
+		{
+			name:     "file extension at any depth",
+			rules:    []Rule{NewRule("r1", Path, "*.go", []string{"team:go"})},
+			event:    event,
+			wantRule: "r1",
+		},
+		{
+			name:  "frames outside the app are ignored",
+			rules: []Rule{NewRule("r1", Module, "github.com/lib/*", []string{"team:db"})},
+			event: event,
+		},
+		{
+			name:     "all frames when none is in-app",
+			rules:    []Rule{NewRule("r1", Module, "github.com/lib/*", []string{"team:db"})},
+			event:    Event{Frames: event.Frames[:1]},
+			wantRule: "r1",
+		},
+		{
+			name:     "tag rule",
+			rules:    []Rule{NewRule("r1", Tag, "customer:enterprise-*", []string{"team:vip"})},
+			event:    event,
+			wantRule: "r1",
+		},
+		{
+			name:  "tag rule on a missing tag",
+			rules: []Rule{NewRule("r1", Tag, "region:*", []string{"team:eu"})},
+			event: event,
+		},
+		{
+			name:     "url rule",
+			rules:    []Rule{NewRule("r1", URL, "*/pay", []string{"team:checkout"})},
+			event:    event,
+			wantRule: "r1",
+		},
+		{
+			name: "last matching rule wins",
+			rules: []Rule{
+				NewRule("r1", Module, "github.com/acme/*", []string{"team:app"}),
+				NewRule("r2", Path, "payments/", []string{"team:billing"}),
+				NewRule("r3", URL, "*/refund", []string{"team:refunds"}),
+			},
+			event:    event,
+			wantRule: "r2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owners, rule, ok := Owners(tt.rules, tt.event)
+			if ok != (tt.wantRule != "") || rule.ID != tt.wantRule {
+				t.Fatalf("Owners matched %q (%v), want %q", rule.ID, ok, tt.wantRule)
+			}
+			if ok && !reflect.DeepEqual(owners, rule.Owners) {
+				t.Errorf("owners = %v, want the rule's %v", owners, rule.Owners)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Package releases orders a project's releases: by semantic version when
+// all of them are one, otherwise all by when each was first seen. Ordering
+// a project's releases by one key keeps the order transitive.
+package releases
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Release is a version with the time it was first seen.
+type Release struct {
+	Version   string
+	FirstSeen time.Time
+}
+
+type semver struct {
+	parts      [3]int
+	prerelease string
+}
+
+// Major versions from this one on are taken for years.
+const minCalendarYear = 1000
+
+// parseSemver accepts "1.2.3", "v1.2", "1.2.3-rc.1+build" and the
+// "package@1.2.3" form Sentry SDKs report. Date-based versions are not
+// semver and are ordered by first-seen time instead.
+func parseSemver(version string) (semver, bool) {
+	var v semver
+
+	if i := strings.LastIndexByte(version, '@'); i >= 0 {
+		version = version[i+1:]
+	}
+	version = strings.TrimPrefix(version, "v")
+
+	if i := strings.IndexByte(version, '+'); i >= 0 {
+		version = version[:i]
+	}
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		version, v.prerelease = version[:i], version[i+1:]
+	}
+
+	fields := strings.Split(version, ".")
+	if len(fields) > 3 {
+		return v, false
+	}
+
+	for i, field := range fields {
+		// Semver forbids leading zeros, which also rules out dates such
+		// as 2024.01.15.
+		if len(field) > 1 && field[0] == '0' {
+			return v, false
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.parts[i] = n
+	}
+
+	// A year as the major version is calendar versioning, e.g. 2024.1.15.
+	if v.parts[0] >= minCalendarYear {
+		return v, false
+	}
+
+	return v, true
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return compareInt(an, bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return compareInt(len(as), len(bs))
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Order is how the releases of a project are ordered.
+type Order int
+
+const (
+	// BySemver orders releases by semantic version, then by first seen.
+	BySemver Order = iota
+	// ByFirstSeen orders releases by when each was first seen.
+	ByFirstSeen
+)
+
+// OrderOf returns the order of a project's releases: by semver when every
+// version is one, otherwise by first seen.
+func OrderOf(versions []string) Order {
+	for _, version := range versions {
+		if _, ok := parseSemver(version); !ok {
+			return ByFirstSeen
+		}
+	}
+	return BySemver
+}
+
+// Compare returns -1, 0 or 1 as a is older than, the same as or newer than b.
+func (o Order) Compare(a, b Release) int {
+	if a.Version == b.Version {
+		return 0
+	}
+
+	if o == BySemver {
+		av, aOK := parseSemver(a.Version)
+		bv, bOK := parseSemver(b.Version)
+		// Both parse when the order was picked with OrderOf.
+		if aOK && bOK {
+			for i := range av.parts {
+				if c := compareInt(av.parts[i], bv.parts[i]); c != 0 {
+					return c
+				}
+			}
+			if c := comparePrerelease(av.prerelease, bv.prerelease); c != 0 {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case a.FirstSeen.Before(b.FirstSeen):
+		return -1
+	case a.FirstSeen.After(b.FirstSeen):
+		return 1
+	}
+	return strings.Compare(a.Version, b.Version)
+}
+
+// Latest returns the newest of a list of releases.
+func (o Order) Latest(list []Release) (Release, bool) {
+	if len(list) == 0 {
+		return Release{}, false
+	}
+
+	latest := list[0]
+	for _, r := range list[1:] {
+		if o.Compare(r, latest) > 0 {
+			latest = r
+		}
+	}
+	return latest, true
+}
@@ -0,0 +1,148 @@
+package releases
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		want     Order
+	}{
+		{name: "no releases", want: BySemver},
+		{name: "semver forms", versions: []string{"1.2.3", "v1.2", "app@1.2.3-rc.1+build", "0.1"}, want: BySemver},
+		{name: "date-based version", versions: []string{"1.2.3", "2024.01.15"}, want: ByFirstSeen},
+		{name: "calendar version", versions: []string{"2024.1.15"}, want: ByFirstSeen},
+		{name: "commit hash", versions: []string{"1.0.0", "9f2c1ab"}, want: ByFirstSeen},
+		{name: "too many fields", versions: []string{"1.2.3.4"}, want: ByFirstSeen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OrderOf(tt.versions); got != tt.want {
+				t.Errorf("OrderOf(%q) = %v, want %v", tt.versions, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name  string
+		order Order
+		a, b  Release
+		want  int
+	}{
+		{
+			name:  "same version",
+			order: BySemver,
+			a:     Release{Version: "1.0.0", FirstSeen: day(1)},
+			b:     Release{Version: "1.0.0", FirstSeen: day(2)},
+			want:  0,
+		},
+		{
+			name:  "semver beats first seen",
+			order: BySemver,
+			a:     Release{Version: "1.10.0", FirstSeen: day(1)},
+			b:     Release{Version: "1.9.0", FirstSeen: day(2)},
+			want:  1,
+		},
+		{
+			name:  "prerelease is older than the release",
+			order: BySemver,
+			a:     Release{Version: "1.0.0-rc.1", FirstSeen: day(2)},
+			b:     Release{Version: "1.0.0", FirstSeen: day(1)},
+			want:  -1,
+		},
+		{
+			name:  "numeric prerelease fields",
+			order: BySemver,
+			a:     Release{Version: "1.0.0-rc.10"},
+			b:     Release{Version: "1.0.0-rc.9"},
+			want:  1,
+		},
+		{
+			name:  "equal semver falls back to first seen",
+			order: BySemver,
+			a:     Release{Version: "v1.0", FirstSeen: day(2)},
+			b:     Release{Version: "1.0.0", FirstSeen: day(1)},
+			want:  1,
+		},
+		{
+			name:  "by first seen ignores semver",
+			order: ByFirstSeen,
+			a:     Release{Version: "1.10.0", FirstSeen: day(1)},
+			b:     Release{Version: "1.9.0", FirstSeen: day(2)},
+			want:  -1,
+		},
+		{
+			name:  "same first seen falls back to the version",
+			order: ByFirstSeen,
+			a:     Release{Version: "b", FirstSeen: day(1)},
+			b:     Release{Version: "a", FirstSeen: day(1)},
+			want:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.order.Compare(tt.a, tt.b); got != tt.want {
+				t.Errorf("Compare(%q, %q) = %d, want %d", tt.a.Version, tt.b.Version, got, tt.want)
+			}
+			if got := tt.order.Compare(tt.b, tt.a); got != -tt.want {
+				t.Errorf("Compare(%q, %q) = %d, want %d", tt.b.Version, tt.a.Version, got, -tt.want)
+			}
+		})
+	}
+}
+
+// Mixing semver and first-seen comparisons made 2.0.0 > 1.0.0 > 2024.01.15
+// > 2.0.0. Ordering the whole project by one key cannot cycle.
+func TestCompareTransitive(t *testing.T) {
+	list := []Release{
+		{Version: "2.0.0", FirstSeen: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Version: "2024.01.15", FirstSeen: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{Version: "1.0.0", FirstSeen: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	var versions []string
+	for _, r := range list {
+		versions = append(versions, r.Version)
+	}
+	order := OrderOf(versions)
+
+	for _, a := range list {
+		for _, b := range list {
+			for _, c := range list {
+				if order.Compare(a, b) > 0 && order.Compare(b, c) > 0 && order.Compare(a, c) <= 0 {
+					t.Errorf("%s > %s > %s but not %s > %s", a.Version, b.Version, c.Version, a.Version, c.Version)
+				}
+			}
+		}
+	}
+
+	latest, _ := order.Latest(list)
+	if latest.Version != "1.0.0" {
+		t.Errorf("Latest = %q, want the last seen %q", latest.Version, "1.0.0")
+	}
+}
+
+func TestLatest(t *testing.T) {
+	if _, ok := BySemver.Latest(nil); ok {
+		t.Error("Latest of no releases reported one")
+	}
+
+	list := []Release{
+		{Version: "1.2.0", FirstSeen: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{Version: "1.10.0", FirstSeen: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Version: "1.10.0-rc.1", FirstSeen: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	latest, ok := BySemver.Latest(list)
+	if !ok || latest.Version != "1.10.0" {
+		t.Errorf("Latest = %q, %v, want %q", latest.Version, ok, "1.10.0")
+	}
+}
@@ -0,0 +1,192 @@
+// Package search parses the issue search language, e.g.
+//
+//	status:open level:error release:v1.2 is:unassigned times_seen:>100 firstSeen:-24h timeout
+//
+// Terms are key:value filters, optionally negated with a leading "!", or
+// free text matched against issue titles. Values containing spaces can be
+// quoted; a term quoted from its start is always free text.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Comparison operators of a filter value such as ">100".
+const (
+	OpEqual        = "="
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+)
+
+type Filter struct {
+	Key    string
+	Op     string
+	Value  string
+	Negate bool
+}
+
+type Query struct {
+	Filters []Filter
+	// Free-text terms, all of which must match.
+	Text []string
+}
+
+type token struct {
+	text string
+	// Quoted from the start, so never a filter.
+	literal bool
+}
+
+// tokens splits a query on whitespace outside double quotes and removes
+// the quotes.
+func tokens(q string) ([]token, error) {
+	var out []token
+	var current strings.Builder
+	inQuotes, started, literal := false, false, false
+
+	for _, r := range q {
+		switch {
+		case r == '"':
+			if !started {
+				literal = true
+			}
+			inQuotes = !inQuotes
+			started = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if started {
+				out = append(out, token{text: current.String(), literal: literal})
+				current.Reset()
+				started, literal = false, false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+
+	if inQuotes {
+		return nil, errors.New("unterminated quote")
+	}
+	if started {
+		out = append(out, token{text: current.String(), literal: literal})
+	}
+	return out, nil
+}
+
+// Parse parses a search query. Keys are lower-cased and camelCase keys are
+// converted to snake_case, so firstSeen and first_seen are the same.
+func Parse(q string) (Query, error) {
+	var query Query
+
+	terms, err := tokens(q)
+	if err != nil {
+		return query, err
+	}
+
+	for _, term := range terms {
+		key, value, ok := strings.Cut(term.text, ":")
+		if term.literal || !ok || key == "" || key == "!" {
+			if term.text != "" {
+				query.Text = append(query.Text, term.text)
+			}
+			continue
+		}
+
+		filter := Filter{Op: OpEqual}
+		if strings.HasPrefix(key, "!") {
+			filter.Negate = true
+			key = key[1:]
+		}
+		filter.Key = snakeCase(key)
+
+		for _, op := range []string{OpGreaterEqual, OpLessEqual, OpGreater, OpLess} {
+			if strings.HasPrefix(value, op) {
+				filter.Op = op
+				value = value[len(op):]
+				break
+			}
+		}
+
+		if value == "" {
+			return query, fmt.Errorf("%s: missing value", key)
+		}
+		filter.Value = value
+
+		query.Filters = append(query.Filters, filter)
+	}
+
+	return query, nil
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ParseAge parses a relative time such as "24h", "30m", "7d" or "2w".
+func ParseAge(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+
+	if unit == 0 {
+		return time.ParseDuration(s)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// TimeBound turns a time filter into a comparison against an absolute time.
+// "-24h" means within the last 24 hours and "+24h" more than 24 hours ago;
+// otherwise the value is an RFC 3339 time or a YYYY-MM-DD date compared with
+// the filter's operator.
+func TimeBound(f Filter, now time.Time) (string, time.Time, error) {
+	if f.Op == OpEqual && (strings.HasPrefix(f.Value, "-") || strings.HasPrefix(f.Value, "+")) {
+		age, err := ParseAge(f.Value[1:])
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if f.Value[0] == '-' {
+			return OpGreaterEqual, now.Add(-age), nil
+		}
+		return OpLess, now.Add(-age), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, f.Value)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", f.Value)
+	}
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid time %q", f.Value)
+	}
+
+	if f.Op == OpEqual {
+		// A bare date means on or after it.
+		return OpGreaterEqual, t, nil
+	}
+	return f.Op, t, nil
+}
@@ -0,0 +1,191 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       string
+		want    Query
+		wantErr bool
+	}{
+		{
+			name: "filters and text",
+			q:    "status:open level:error timeout",
+			want: Query{
+				Filters: []Filter{
+					{Key: "status", Op: OpEqual, Value: "open"},
+					{Key: "level", Op: OpEqual, Value: "error"},
+				},
+				Text: []string{"timeout"},
+			},
+		},
+		{
+			name: "operators",
+			q:    "times_seen:>100 times_seen:<=5 user_count:>=2 age:<1",
+			want: Query{Filters: []Filter{
+				{Key: "times_seen", Op: OpGreater, Value: "100"},
+				{Key: "times_seen", Op: OpLessEqual, Value: "5"},
+				{Key: "user_count", Op: OpGreaterEqual, Value: "2"},
+				{Key: "age", Op: OpLess, Value: "1"},
+			}},
+		},
+		{
+			name: "negation and camelCase keys",
+			q:    "!is:assigned firstSeen:-24h",
+			want: Query{Filters: []Filter{
+				{Key: "is", Op: OpEqual, Value: "assigned", Negate: true},
+				{Key: "first_seen", Op: OpEqual, Value: "-24h"},
+			}},
+		},
+		{
+			name: "quoted value",
+			q:    `release:"my app 1.0"   "connection refused"`,
+			want: Query{
+				Filters: []Filter{{Key: "release", Op: OpEqual, Value: "my app 1.0"}},
+				Text:    []string{"connection refused"},
+			},
+		},
+		{
+			name: "quoted from the start is text",
+			q:    `"status:open"`,
+			want: Query{Text: []string{"status:open"}},
+		},
+		{
+			name: "terms without a key are text",
+			q:    ":x !:y http://host",
+			want: Query{
+				Filters: []Filter{{Key: "http", Op: OpEqual, Value: "//host"}},
+				Text:    []string{":x", "!:y"},
+			},
+		},
+		{
+			name: "empty quotes are dropped",
+			q:    `"" boom`,
+			want: Query{Text: []string{"boom"}},
+		},
+		{
+			name:    "missing value",
+			q:       "times_seen:>",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated quote",
+			q:       `release:"v1`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.q)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.q, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{s: "30m", want: 30 * time.Minute},
+		{s: "24h", want: 24 * time.Hour},
+		{s: "7d", want: 7 * 24 * time.Hour},
+		{s: "2w", want: 14 * 24 * time.Hour},
+		{s: "xd", wantErr: true},
+		{s: "-1d", wantErr: true},
+		{s: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAge(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAge(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTimeBound(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantOp  string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:   "within the last day",
+			filter: Filter{Op: OpEqual, Value: "-24h"},
+			wantOp: OpGreaterEqual,
+			want:   now.Add(-24 * time.Hour),
+		},
+		{
+			name:   "more than a week ago",
+			filter: Filter{Op: OpEqual, Value: "+1w"},
+			wantOp: OpLess,
+			want:   now.Add(-7 * 24 * time.Hour),
+		},
+		{
+			name:   "bare date means on or after",
+			filter: Filter{Op: OpEqual, Value: "2024-05-01"},
+			wantOp: OpGreaterEqual,
+			want:   time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "RFC 3339 time with an operator",
+			filter: Filter{Op: OpLess, Value: "2024-05-01T08:30:00Z"},
+			wantOp: OpLess,
+			want:   time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid age",
+			filter:  Filter{Op: OpEqual, Value: "-soon"},
+			wantErr: true,
+		},
+		{
+			name:    "relative value with an operator",
+			filter:  Filter{Op: OpGreater, Value: "-24h"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid time",
+			filter:  Filter{Op: OpEqual, Value: "yesterday"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, got, err := TimeBound(tt.filter, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TimeBound error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if op != tt.wantOp || !got.Equal(tt.want) {
+				t.Errorf("TimeBound = %s %v, want %s %v", op, got, tt.wantOp, tt.want)
+			}
+		})
+	}
+}
@@ -12,14 +12,18 @@ import (
 	"github.com/k1ngalph0x/beacon/services/kafka-service/models"
 	"github.com/segmentio/kafka-go"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Event struct {
+	EventID    string    `json:"event_id"`
 	ProjectID  string    `json:"project_id"`
 	Timestamp  time.Time `json:"timestamp"`
 	Level      string    `json:"level"`
 	Message    string    `json:"message"`
 	StackTrace *string    `json:"stack_trace,omitempty"`
+	Environment string    `json:"environment,omitempty"`
+	Release    string     `json:"release,omitempty"`
 }
 
 
@@ -41,6 +45,11 @@ func main() {
 		log.Fatalf("Failed to migrate User table: %v", err)
 	}
 
+	err = migrateEventsKey(conn)
+	if err != nil{
+		log.Fatalf("Failed to migrate events primary key: %v", err)
+	}
+
 
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers: config.KAFKA.Brokers,
@@ -85,22 +94,56 @@ func main() {
 
 
 
+// migrateEventsKey re-keys an events table created when the event ID alone
+// was the primary key. AutoMigrate does not change primary keys.
+func migrateEventsKey(db *gorm.DB) error {
+	var columns int64
+	err := db.Raw(`SELECT COUNT(*) FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = 'events'::regclass AND i.indisprimary`).Scan(&columns).Error
+	if err != nil || columns != 1 {
+		return err
+	}
+
+	return db.Exec(`ALTER TABLE events DROP CONSTRAINT events_pkey, ADD PRIMARY KEY (project_id, id)`).Error
+}
+
 func insertEvent(db *gorm.DB, e Event, partition int, offset int64) error {
 	event := models.Events{
+		ID:             e.EventID,
 		ProjectID:      e.ProjectID,
 		Level:          e.Level,
 		Message:        e.Message,
 		StackTrace:     e.StackTrace,  
+		Environment:    e.Environment,
+		Release:        e.Release,
 		EventTimestamp: e.Timestamp,
 		KafkaPartition: &partition,     
 		KafkaOffset:    &offset,  
 	}
 
-	result := db.Create(&event)
+	// The project and event ID are the primary key, so a redelivered
+	// message is a no-op.
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
 	if result.Error != nil{
 		return result.Error
 	}
 
+	if result.RowsAffected == 0 {
+		fmt.Println("Skipping duplicate event:", e.EventID)
+		return nil
+	}
+
+	// Issue Service may have grouped the event before it was stored here.
+	if db.Migrator().HasTable("issue_events") {
+		err := db.Exec(`UPDATE events SET issue_id = issue_events.issue_id, fingerprint = issue_events.fingerprint
+			FROM issue_events WHERE issue_events.project_id = events.project_id AND issue_events.event_id = events.id
+			AND events.project_id = ? AND events.id = ?`, event.ProjectID, event.ID).Error
+		if err != nil {
+			fmt.Println("Failed to link event to its issue:", err)
+		}
+	}
+
 	fmt.Println("Successfully inserted to db")
 
 	return nil
@@ -7,16 +7,24 @@ import (
 	"gorm.io/gorm"
 )
 
+// Events is keyed by project and ID: clients choose event IDs, so they are
+// only unique within a project.
 type Events struct {
+	ProjectID      string    `gorm:"type:text;primaryKey;index:idx_beacon_events_project_id" json:"project_id"`
 	ID             string    `gorm:"type:uuid;primaryKey" json:"id"`
-	ProjectID      string    `gorm:"type:text;not null;index:idx_beacon_events_project_id" json:"project_id"`
 	Level          string    `gorm:"type:text;not null;index:idx_beacon_events_level" json:"level"`
 	Message        string    `gorm:"type:text;not null" json:"message"`
 	StackTrace     *string   `gorm:"type:text" json:"stack_trace,omitempty"` 
+	Environment    string    `gorm:"type:text" json:"environment,omitempty"`
+	Release        string    `gorm:"type:text" json:"release,omitempty"`
 	EventTimestamp time.Time `gorm:"not null;index:idx_beacon_events_timestamp" json:"event_timestamp"`
 	ReceivedAt     time.Time `gorm:"not null;autoCreateTime" json:"received_at"`
 	KafkaPartition *int      `gorm:"type:int" json:"kafka_partition,omitempty"` 
 	KafkaOffset    *int64    `gorm:"type:bigint" json:"kafka_offset,omitempty"` 
+	// Issue the event was grouped into and the fingerprint that matched.
+	// Issue Service sets them; they are empty until it has seen the event.
+	Fingerprint    string    `gorm:"type:text" json:"fingerprint,omitempty"`
+	IssueID        *string   `gorm:"type:uuid;index:idx_beacon_events_issue_id" json:"issue_id,omitempty"`
 }
 
 func (e *Events) BeforeCreate(tx *gorm.DB) error {